@@ -4,12 +4,21 @@ import (
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
 type Config struct {
-	HTTP   HTTP   `yaml:"http"`
-	DB     DB     `yaml:"db"`
-	Logger Logger `yaml:"logger"`
+	HTTP     HTTP     `yaml:"http"`
+	DB       DB       `yaml:"db"`
+	Logger   Logger   `yaml:"logger"`
+	History  History  `yaml:"history"`
+	Job      Job      `yaml:"job"`
+	Console  Console  `yaml:"console"`
+	Health   Health   `yaml:"health"`
+	Snapshot Snapshot `yaml:"snapshot"`
+	Metadata Metadata `yaml:"metadata"`
+	SQLite   SQLite   `yaml:"sqlite"`
+	Diagnose Diagnose `yaml:"diagnose"`
 }
 
 type HTTP struct {
@@ -26,6 +35,52 @@ type Logger struct {
 	DisableStacktrace bool `yaml:"disable_stacktrace"`
 }
 
+type History struct {
+	RedactArgs bool `yaml:"redact_args"`
+}
+
+type Job struct {
+	Workers   int           `yaml:"workers"`
+	QueueSize int           `yaml:"queue_size"`
+	Dir       string        `yaml:"dir"`
+	TTL       time.Duration `yaml:"ttl"`
+}
+
+// Console ограничивает произвольный SQL.
+type Console struct {
+	Timeout  time.Duration `yaml:"timeout"`
+	RowLimit int           `yaml:"row_limit"`
+}
+
+// Health - фоновая проверка подключенных баз данных.
+type Health struct {
+	Interval   time.Duration `yaml:"interval"`    //0 - проверка выключена
+	Timeout    time.Duration `yaml:"timeout"`     //0 - равен Interval
+	Degraded   time.Duration `yaml:"degraded"`    //время ответа, после которого база данных считается деградировавшей
+	MaxBackoff time.Duration `yaml:"max_backoff"` //наибольший интервал проверки недоступной базы данных
+}
+
+// Diagnose - проверка подключения перед добавлением базы данных.
+type Diagnose struct {
+	Timeout time.Duration `yaml:"timeout"` //0 - без ограничения
+}
+
+// Snapshot - сохранение снимков схемы подключенных баз данных.
+type Snapshot struct {
+	Interval time.Duration `yaml:"interval"` //0 - снимки только по запросу
+	Timeout  time.Duration `yaml:"timeout"`  //чтение схемы одной базы данных; 0 - без ограничения
+}
+
+// Metadata - кеш таблиц и функций подключенных баз данных.
+type Metadata struct {
+	TTL time.Duration `yaml:"ttl"` //0 - метаданные читаются при каждом запросе
+}
+
+// SQLite ограничивает файлы, которые можно подключить как базу данных SQLite.
+type SQLite struct {
+	Dir string `yaml:"dir"` //пустое значение - подключение файлов SQLite выключено
+}
+
 func Must() *Config {
 	cfg := new(Config)
 
@@ -4,7 +4,12 @@ import (
 	"datapoint/config"
 	httpcontroller "datapoint/internal/controller/http"
 	"datapoint/internal/repo/dbrepo"
+	"datapoint/internal/repo/historyrepo"
+	"datapoint/internal/repo/snapshotrepo"
 	"datapoint/internal/service/dbservice"
+	"datapoint/internal/service/jobservice"
+	"datapoint/internal/service/queryservice"
+	"datapoint/internal/service/snapshotservice"
 	"datapoint/migration"
 	"datapoint/pkg/database"
 	"github.com/go-playground/validator/v10"
@@ -33,7 +38,7 @@ func Run(cfg *config.Config) error {
 		Next: func(c fiber.Ctx) bool { return false },
 	}))
 
-	db, err := database.New(cfg.DB.Driver, cfg.DB.DSN)
+	db, err := database.New(cfg.DB.Driver, cfg.DB.DSN, nil)
 	if err != nil {
 		return err
 	}
@@ -43,13 +48,26 @@ func Run(cfg *config.Config) error {
 	}
 
 	dbRepo := dbrepo.New(db)
+	snapshotRepo := snapshotrepo.New(db)
 
-	dbService, err := dbservice.New(dbRepo, db)
+	dbService, err := dbservice.New(dbRepo, snapshotRepo, db, cfg.Health, cfg.Metadata, cfg.SQLite, cfg.Diagnose, cfg.DB)
 	if err != nil {
 		return err
 	}
+	defer dbService.Close()
 
-	httpcontroller.New(app, v, dbService)
+	queryService := queryservice.New(dbService, historyrepo.New(db), cfg.History, cfg.Console)
+
+	jobService, err := jobservice.New(queryService, cfg.Job, cfg.Console)
+	if err != nil {
+		return err
+	}
+	defer jobService.Close()
+
+	snapshotService := snapshotservice.New(dbService, snapshotRepo, dbRepo, db, cfg.Snapshot)
+	defer snapshotService.Close()
+
+	httpcontroller.New(app, v, dbService, queryService, jobService, snapshotService)
 
 	return app.Listen(cfg.HTTP.Addr)
 }
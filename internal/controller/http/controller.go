@@ -2,6 +2,9 @@ package httpcontroller
 
 import (
 	"datapoint/internal/controller/http/dbcontroller"
+	"datapoint/internal/controller/http/jobcontroller"
+	"datapoint/internal/controller/http/querycontroller"
+	"datapoint/internal/controller/http/snapshotcontroller"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
 )
@@ -10,6 +13,12 @@ func New(
 	r fiber.Router,
 	v *validator.Validate,
 	dbService dbcontroller.Service,
+	queryService querycontroller.Service,
+	jobService jobcontroller.Service,
+	snapshotService snapshotcontroller.Service,
 ) {
 	dbcontroller.New(r, dbService, v)
+	querycontroller.New(r, queryService, v)
+	jobcontroller.New(r, jobService, v)
+	snapshotcontroller.New(r, snapshotService, v)
 }
@@ -1,9 +1,12 @@
 package converter
 
 import (
+	"database/sql"
 	"datapoint/internal/controller/http/model"
 	"datapoint/internal/model/dbmodel"
+	"datapoint/pkg/database"
 	"datapoint/pkg/slices"
+	"time"
 )
 
 func ToDBInfo(i dbmodel.Info) model.DBInfo {
@@ -15,14 +18,45 @@ func ToDBInfo(i dbmodel.Info) model.DBInfo {
 		Password: i.Config.Password,
 		DBName:   i.Config.Name,
 		Driver:   i.Config.Driver,
+		Path:     i.Config.Path,
+		ReadOnly: i.Config.ReadOnly,
+
+		SSLMode:     i.Config.SSLMode,
+		SSLRootCert: i.Config.SSLRootCert,
+		SSLCert:     i.Config.SSLCert,
+		SSLKeySet:   len(i.Config.SSLKey) != 0,
+
+		SSH: ToDBSSH(i.Config.SSH),
+
+		Params:        i.Config.Params,
+		ConnStringSet: len(i.Config.ConnString) != 0,
+
+		Pool: ToDBPool(i.Config.Pool),
+
+		FunctionList: i.FunctionList,
+		SchemaList:   i.SchemaList,
+		AllowWrite:   i.AllowWrite,
 	}
 }
 
 func ToDB(d *dbmodel.DB) model.DB {
 	return model.DB{
 		ID:     d.ID,
-		DBInfo: ToDBInfo(d.Info),
+		DBInfo: ToDBInfo(d.GetInfo()),
+		Health: ToDBHealth(d.Health()),
+	}
+}
+
+func ToDBHealth(h dbmodel.Health) model.DBHealth {
+	health := model.DBHealth{
+		Status:  h.Status,
+		Error:   h.Error,
+		Latency: h.Latency.Milliseconds(),
+	}
+	if !h.CheckedAt.IsZero() {
+		health.CheckedAt = &h.CheckedAt
 	}
+	return health
 }
 
 func ToDBList(list []*dbmodel.DB) []model.DB {
@@ -39,16 +73,134 @@ func FromDBInfo(i model.DBInfo) dbmodel.Info {
 			Password: i.Password,
 			Name:     i.DBName,
 			Driver:   i.Driver,
+			Path:     i.Path,
+			ReadOnly: i.ReadOnly,
+
+			SSLMode:     i.SSLMode,
+			SSLRootCert: i.SSLRootCert,
+			SSLCert:     i.SSLCert,
+			SSLKey:      i.SSLKey,
+
+			SSH: FromDBSSH(i.SSH),
+
+			Params:     i.Params,
+			ConnString: i.ConnString,
+
+			Pool: FromDBPool(i.Pool),
 		},
+		FunctionList: i.FunctionList,
+		SchemaList:   i.SchemaList,
+		AllowWrite:   i.AllowWrite,
+	}
+}
+
+func ToDBSSH(cfg *database.SSHConfig) *model.DBSSH {
+	if cfg == nil {
+		return nil
+	}
+
+	return &model.DBSSH{
+		Host:            cfg.Host,
+		Port:            int(cfg.Port),
+		User:            cfg.User,
+		PasswordSet:     len(cfg.Password) != 0,
+		PrivateKeySet:   len(cfg.PrivateKey) != 0,
+		KnownHosts:      cfg.KnownHosts,
+		InsecureHostKey: cfg.InsecureHostKey,
+	}
+}
+
+func FromDBSSH(s *model.DBSSH) *database.SSHConfig {
+	if s == nil {
+		return nil
+	}
+
+	return &database.SSHConfig{
+		Host:            s.Host,
+		Port:            uint16(s.Port),
+		User:            s.User,
+		Password:        s.Password,
+		PrivateKey:      s.PrivateKey,
+		Passphrase:      s.Passphrase,
+		KnownHosts:      s.KnownHosts,
+		InsecureHostKey: s.InsecureHostKey,
+	}
+}
+
+func ToDBPool(cfg database.PoolConfig) *model.DBPool {
+	if cfg == (database.PoolConfig{}) {
+		return nil
+	}
+
+	return &model.DBPool{
+		MaxOpen:     cfg.MaxOpen,
+		MaxIdle:     cfg.MaxIdle,
+		MaxLifetime: cfg.MaxLifetime.Milliseconds(),
+		MaxIdleTime: cfg.MaxIdleTime.Milliseconds(),
+	}
+}
+
+func FromDBPool(p *model.DBPool) database.PoolConfig {
+	if p == nil {
+		return database.PoolConfig{}
+	}
+
+	return database.PoolConfig{
+		MaxOpen:     p.MaxOpen,
+		MaxIdle:     p.MaxIdle,
+		MaxLifetime: time.Duration(p.MaxLifetime) * time.Millisecond,
+		MaxIdleTime: time.Duration(p.MaxIdleTime) * time.Millisecond,
+	}
+}
+
+func ToDBStats(s sql.DBStats) model.DBStats {
+	return model.DBStats{
+		MaxOpen:           s.MaxOpenConnections,
+		Open:              s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDuration:      s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+func ToDBStep(s *dbmodel.Step) model.DBStep {
+	return model.DBStep{
+		Name:     s.Name,
+		Target:   s.Target,
+		Status:   s.Status,
+		Error:    s.Error,
+		Duration: s.Duration.Milliseconds(),
 	}
 }
 
+func ToDBDiagnosis(d dbmodel.Diagnosis) model.DBDiagnosis {
+	diagnosis := model.DBDiagnosis{
+		OK:       d.OK,
+		StepList: slices.Map(d.StepList, ToDBStep),
+	}
+
+	if d.Server != nil {
+		diagnosis.Server = &model.DBServerInfo{
+			Version:    d.Server.Version,
+			User:       d.Server.User,
+			Privileges: d.Server.Privileges,
+		}
+	}
+
+	return diagnosis
+}
+
 func ToDBfk(fk *dbmodel.FK) *model.DBfk {
 	if fk == nil {
 		return nil
 	}
 
 	return &model.DBfk{
+		Schema:     fk.Schema,
 		TableName:  fk.TableName,
 		ColumnName: fk.ColumnName,
 	}
@@ -61,6 +213,18 @@ func ToDBColumn(c *dbmodel.Column) model.DBColumn {
 		IsRequired: c.IsRequired,
 		IsPK:       c.IsPK,
 		FK:         ToDBfk(c.FK),
+
+		Default:     c.Default,
+		IsIdentity:  c.IsIdentity,
+		IsSerial:    c.IsSerial,
+		IsGenerated: c.IsGenerated,
+		Generated:   c.Generated,
+		MaxLength:   c.MaxLength,
+		Precision:   c.Precision,
+		Scale:       c.Scale,
+		ElementType: c.ElementType,
+		EnumList:    c.EnumList,
+		Comment:     c.Comment,
 	}
 }
 
@@ -70,8 +234,52 @@ func ToDBColumnList(list []*dbmodel.Column) []model.DBColumn {
 
 func ToDBTable(t *dbmodel.Table) model.DBTable {
 	return model.DBTable{
+		Schema:     t.Schema,
 		Name:       t.Name,
+		Kind:       t.Kind,
+		Definition: t.Definition,
+		Comment:    t.Comment,
 		ColumnList: ToDBColumnList(t.ColumnList),
+		IndexList:  slices.Map(t.IndexList, ToDBIndex),
+		UniqueList: slices.Map(t.UniqueList, ToDBUnique),
+		CheckList:  slices.Map(t.CheckList, ToDBCheck),
+		FKList:     slices.Map(t.FKList, ToDBFKey),
+	}
+}
+
+func ToDBIndex(i *dbmodel.Index) model.DBIndex {
+	return model.DBIndex{
+		Name:       i.Name,
+		ColumnList: i.ColumnList,
+		IsUnique:   i.IsUnique,
+		Method:     i.Method,
+		Predicate:  i.Predicate,
+	}
+}
+
+func ToDBUnique(c *dbmodel.Constraint) model.DBUnique {
+	return model.DBUnique{
+		Name:       c.Name,
+		ColumnList: c.ColumnList,
+	}
+}
+
+func ToDBCheck(c *dbmodel.Constraint) model.DBCheck {
+	return model.DBCheck{
+		Name:       c.Name,
+		Expression: c.Expression,
+	}
+}
+
+func ToDBFKey(fk *dbmodel.ForeignKey) model.DBFKey {
+	return model.DBFKey{
+		Name:          fk.Name,
+		ColumnList:    fk.ColumnList,
+		RefSchema:     fk.RefSchema,
+		RefTable:      fk.RefTable,
+		RefColumnList: fk.RefColumnList,
+		OnDelete:      fk.OnDelete,
+		OnUpdate:      fk.OnUpdate,
 	}
 }
 
@@ -79,10 +287,19 @@ func ToDBTableList(list []*dbmodel.Table) []model.DBTable {
 	return slices.Map(list, ToDBTable)
 }
 
+func ToDBSignature(s *dbmodel.Signature) model.DBSignature {
+	return model.DBSignature{
+		ArgTypeList: s.ArgTypeList,
+		ReturnType:  s.ReturnType,
+	}
+}
+
 func ToDBFunction(f *dbmodel.Function) model.DBFunction {
 	return model.DBFunction{
-		Name:     f.Name,
-		TypeList: f.TypeList,
+		Name:          f.Name,
+		Kind:          f.Kind,
+		TypeList:      f.TypeList,
+		SignatureList: slices.Map(f.SignatureList, ToDBSignature),
 	}
 }
 
@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"datapoint/internal/controller/http/model"
+	"datapoint/internal/model/jobmodel"
+	"datapoint/internal/model/querymodel"
+	"datapoint/pkg/slices"
+)
+
+func ToJob(j jobmodel.Job) model.Job {
+	return model.Job{
+		ID:         j.ID,
+		DBID:       j.DBID,
+		Info:       querymodel.NewDocument(j.Info),
+		Status:     j.Status,
+		Error:      j.Error,
+		RowCount:   j.RowCount,
+		Truncated:  j.Truncated,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+		ExpiresAt:  j.ExpiresAt,
+	}
+}
+
+func ToJobList(list []jobmodel.Job) []model.Job {
+	return slices.Map(list, ToJob)
+}
+
+func ToJobPage(p jobmodel.Page) model.JobPage {
+	return model.JobPage{
+		Data:     p.Data,
+		RowCount: p.RowCount,
+		Limit:    p.Limit,
+		Offset:   p.Offset,
+	}
+}
@@ -0,0 +1,108 @@
+package converter
+
+import (
+	"datapoint/internal/controller/http/model"
+	"datapoint/internal/model/historymodel"
+	"datapoint/internal/model/querymodel"
+	"datapoint/pkg/slices"
+	"time"
+)
+
+func ToQueryResult(r querymodel.QueryResult) model.QueryResult {
+	return model.QueryResult{
+		Columns:   slices.Map(r.Columns, ToResultColumn),
+		Data:      r.Data,
+		RowCount:  r.RowCount,
+		Truncated: r.Truncated,
+		Sampled:   r.Sampled,
+	}
+}
+
+func ToResultColumn(c querymodel.ResultColumn) model.ResultColumn {
+	return model.ResultColumn{
+		Name: c.Name,
+		Type: c.Type,
+	}
+}
+
+func FromHistoryFilter(f model.HistoryFilter) historymodel.Filter {
+	return historymodel.Filter{
+		DBID:   f.DBID,
+		Type:   f.Type,
+		Failed: f.Failed,
+		From:   parseTime(f.From),
+		To:     parseTime(f.To),
+		Limit:  f.Limit,
+		Offset: f.Offset,
+	}
+}
+
+// parseTime ожидает строку, уже проверенную валидатором.
+func parseTime(s string) *time.Time {
+	if len(s) == 0 {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+
+	t = t.UTC()
+	return &t
+}
+
+func ToHistoryEntry(e *historymodel.Entry) model.HistoryEntry {
+	entry := model.HistoryEntry{
+		ID:        e.ID,
+		DBID:      e.DBID,
+		Query:     e.Query,
+		Args:      e.Args,
+		Duration:  e.Duration.Milliseconds(),
+		RowCount:  e.RowCount,
+		Error:     e.Error,
+		Caller:    e.Caller,
+		Redacted:  e.Redacted,
+		CreatedAt: e.CreatedAt,
+	}
+
+	if !e.Raw {
+		d := querymodel.NewDocument(e.Info)
+		entry.Info = &d
+	}
+
+	return entry
+}
+
+func ToHistoryEntryList(list []*historymodel.Entry) []model.HistoryEntry {
+	return slices.Map(list, ToHistoryEntry)
+}
+
+func ToRunning(r querymodel.Running) model.Running {
+	running := model.Running{
+		ID:        r.ID,
+		DBID:      r.DBID,
+		Query:     r.Query,
+		Redacted:  r.Redacted,
+		StartedAt: r.StartedAt,
+	}
+
+	if len(r.Query) == 0 {
+		d := querymodel.NewDocument(r.Info)
+		running.Info = &d
+	}
+
+	return running
+}
+
+func ToRunningList(list []querymodel.Running) []model.Running {
+	return slices.Map(list, ToRunning)
+}
+
+func ToParseError(e *querymodel.ParseError) model.ParseError {
+	return model.ParseError{
+		Position:    e.Pos,
+		Message:     e.Error(),
+		Unsupported: e.Unsupported,
+	}
+}
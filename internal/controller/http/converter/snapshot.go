@@ -0,0 +1,50 @@
+package converter
+
+import (
+	"datapoint/internal/controller/http/model"
+	"datapoint/internal/model/snapshotmodel"
+	"datapoint/pkg/slices"
+)
+
+func ToSnapshot(s *snapshotmodel.Snapshot) model.Snapshot {
+	return model.Snapshot{
+		Version:   s.Version,
+		Hash:      s.Hash,
+		CreatedAt: s.CreatedAt,
+		TableList: slices.Map(s.TableList, ToDBTable),
+	}
+}
+
+func ToSnapshotList(list []*snapshotmodel.Snapshot) []model.Snapshot {
+	return slices.Map(list, ToSnapshot)
+}
+
+func ToSnapshotDiff(d snapshotmodel.Diff) model.SnapshotDiff {
+	return model.SnapshotDiff{
+		From:          d.From,
+		To:            d.To,
+		AddedTables:   d.AddedTables,
+		RemovedTables: d.RemovedTables,
+		TableList:     slices.Map(d.TableList, ToSnapshotTableDiff),
+	}
+}
+
+func ToSnapshotTableDiff(d *snapshotmodel.TableDiff) model.SnapshotTableDiff {
+	return model.SnapshotTableDiff{
+		Schema:         d.Schema,
+		Name:           d.Name,
+		AddedColumns:   d.AddedColumns,
+		RemovedColumns: d.RemovedColumns,
+		ColumnList:     slices.Map(d.ColumnList, ToSnapshotColumnDiff),
+		AddedFKs:       slices.Map(d.AddedFKs, ToDBFKey),
+		RemovedFKs:     slices.Map(d.RemovedFKs, ToDBFKey),
+	}
+}
+
+func ToSnapshotColumnDiff(d *snapshotmodel.ColumnDiff) model.SnapshotColumnDiff {
+	return model.SnapshotColumnDiff{
+		Name:     d.Name,
+		FromType: d.FromType,
+		ToType:   d.ToType,
+	}
+}
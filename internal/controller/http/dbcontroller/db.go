@@ -2,6 +2,7 @@ package dbcontroller
 
 import (
 	"context"
+	"database/sql"
 	"datapoint/internal/controller/http/converter"
 	"datapoint/internal/controller/http/model"
 	"datapoint/internal/model/dbmodel"
@@ -12,10 +13,13 @@ import (
 type Service interface {
 	GetList() []*dbmodel.DB
 	Add(ctx context.Context, info dbmodel.Info) (string, error)
+	Test(ctx context.Context, info dbmodel.Info) dbmodel.Diagnosis
 	Edit(ctx context.Context, info dbmodel.Info, id string) error
 	Delete(ctx context.Context, id string) error
-	TableList(ctx context.Context, id string) ([]*dbmodel.Table, error)
-	FunctionList(ctx context.Context, id string) ([]*dbmodel.Function, error)
+	SchemaList(ctx context.Context, id string) ([]string, error)
+	Metadata(ctx context.Context, id string) (*dbmodel.Metadata, error)
+	Refresh(ctx context.Context, id string) (*dbmodel.Metadata, error)
+	Stats(id string) (sql.DBStats, error)
 }
 
 type controller struct {
@@ -45,6 +49,17 @@ func (c *controller) add(ctx fiber.Ctx) error {
 		SendString(id)
 }
 
+func (c *controller) test(ctx fiber.Ctx) error {
+	var body model.DBInfo
+
+	err := ctx.Bind().JSON(&body)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return ctx.JSON(converter.ToDBDiagnosis(c.s.Test(ctx.Context(), converter.FromDBInfo(body))))
+}
+
 func (c *controller) edit(ctx fiber.Ctx) error {
 	id := ctx.Params("id")
 	err := c.v.Var(id, "uuid")
@@ -74,6 +89,21 @@ func (c *controller) delete(ctx fiber.Ctx) error {
 	return c.s.Delete(ctx.Context(), id)
 }
 
+func (c *controller) schemaList(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var list []string
+	if list, err = c.s.SchemaList(ctx.Context(), id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(list)
+}
+
 func (c *controller) tableList(ctx fiber.Ctx) error {
 	id := ctx.Params("id")
 	err := c.v.Var(id, "uuid")
@@ -81,12 +111,40 @@ func (c *controller) tableList(ctx fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	var list []*dbmodel.Table
-	if list, err = c.s.TableList(ctx.Context(), id); err != nil {
+	var m *dbmodel.Metadata
+	if m, err = c.s.Metadata(ctx.Context(), id); err != nil {
 		return err
 	}
 
-	return ctx.JSON(converter.ToDBTableList(list))
+	if notModified(ctx, m) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.JSON(converter.ToDBTableList(m.TableList))
+}
+
+func (c *controller) table(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var m *dbmodel.Metadata
+	if m, err = c.s.Metadata(ctx.Context(), id); err != nil {
+		return err
+	}
+
+	var t *dbmodel.Table
+	if t, err = m.TableByName(ctx.Params("name")); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if notModified(ctx, m) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.JSON(converter.ToDBTable(t))
 }
 
 func (c *controller) functionList(ctx fiber.Ctx) error {
@@ -96,16 +154,59 @@ func (c *controller) functionList(ctx fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	var list []*dbmodel.Function
-	if list, err = c.s.FunctionList(ctx.Context(), id); err != nil {
+	var m *dbmodel.Metadata
+	if m, err = c.s.Metadata(ctx.Context(), id); err != nil {
+		return err
+	}
+
+	if notModified(ctx, m) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.JSON(converter.ToDBFunctionList(m.FunctionList))
+}
+
+func (c *controller) refresh(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var m *dbmodel.Metadata
+	if m, err = c.s.Refresh(ctx.Context(), id); err != nil {
+		return err
+	}
+
+	ctx.Set(fiber.HeaderETag, m.ETag)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// notModified выставляет ETag метаданных и сообщает, что у клиента они уже есть (If-None-Match).
+func notModified(ctx fiber.Ctx, m *dbmodel.Metadata) bool {
+	ctx.Set(fiber.HeaderETag, m.ETag)
+	//клиент должен каждый раз сверять ETag: метаданные могут измениться в любой момент
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	return ctx.Fresh()
+}
+
+func (c *controller) stats(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var stats sql.DBStats
+	if stats, err = c.s.Stats(id); err != nil {
 		return err
 	}
 
-	return ctx.JSON(converter.ToDBFunctionList(list))
+	return ctx.JSON(converter.ToDBStats(stats))
 }
 
 func (c *controller) driverList(ctx fiber.Ctx) error {
-	return ctx.JSON([...]string{dbmodel.PostgreSQL})
+	return ctx.JSON(dbmodel.DriverList())
 }
 
 func New(r fiber.Router, s Service, v *validator.Validate) {
@@ -113,9 +214,14 @@ func New(r fiber.Router, s Service, v *validator.Validate) {
 	g := r.Group("/database")
 	g.Get("/", c.getList)
 	g.Post("/", c.add)
+	g.Post("/test", c.test)
 	g.Patch("/:id", c.edit)
 	g.Delete("/:id", c.delete)
 	g.Get("/:id", c.tableList)
-	g.Get("/:id", c.functionList)
+	g.Get("/:id/table/:name", c.table)
+	g.Get("/:id/schema", c.schemaList)
+	g.Get("/:id/function", c.functionList)
+	g.Post("/:id/refresh", c.refresh)
+	g.Get("/:id/stats", c.stats)
 	r.Get("/driver", c.driverList)
 }
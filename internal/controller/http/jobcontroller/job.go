@@ -0,0 +1,109 @@
+package jobcontroller
+
+import (
+	"datapoint/internal/controller/http/converter"
+	"datapoint/internal/controller/http/model"
+	"datapoint/internal/model/jobmodel"
+	"datapoint/internal/model/querymodel"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+)
+
+type Service interface {
+	Add(info querymodel.Info, id string) (string, error)
+	GetByID(jid string) (jobmodel.Job, error)
+	GetList() []jobmodel.Job
+	Result(jid string, limit, offset uint64) (jobmodel.Page, error)
+}
+
+const (
+	defaultLimit = 100
+	maxLimit     = 10000 //совпадает с validate:"max" в model.PageFilter
+)
+
+type controller struct {
+	s Service
+	v *validator.Validate
+}
+
+func (c *controller) add(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var body querymodel.Info
+	if body, err = querymodel.Decode(ctx.Body()); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var jid string
+	if jid, err = c.s.Add(body, id); err != nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	}
+
+	return ctx.
+		Status(fiber.StatusAccepted).
+		SendString(jid)
+}
+
+func (c *controller) getList(ctx fiber.Ctx) error {
+	return ctx.JSON(converter.ToJobList(c.s.GetList()))
+}
+
+func (c *controller) getByID(ctx fiber.Ctx) error {
+	jid := ctx.Params("jid")
+	err := c.v.Var(jid, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var j jobmodel.Job
+	if j, err = c.s.GetByID(jid); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return ctx.JSON(converter.ToJob(j))
+}
+
+func (c *controller) result(ctx fiber.Ctx) error {
+	jid := ctx.Params("jid")
+	err := c.v.Var(jid, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	filter := model.PageFilter{Limit: defaultLimit}
+	if err = ctx.Bind().Query(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	//limit=0 означает весь результат, а он может не поместиться в один ответ
+	if filter.Limit == 0 {
+		filter.Limit = maxLimit
+	}
+
+	var page jobmodel.Page
+	if page, err = c.s.Result(jid, filter.Limit, filter.Offset); err != nil {
+		switch {
+		case errors.Is(err, jobmodel.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, jobmodel.ErrNotDone):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(converter.ToJobPage(page))
+}
+
+func New(r fiber.Router, s Service, v *validator.Validate) {
+	c := controller{s: s, v: v}
+	g := r.Group("/job")
+	g.Get("/", c.getList)
+	g.Post("/:id", c.add)
+	g.Get("/:jid", c.getByID)
+	g.Get("/:jid/result", c.result)
+}
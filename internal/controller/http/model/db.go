@@ -1,21 +1,105 @@
 package model
 
+import "time"
+
 type DB struct {
 	ID string `json:"id"`
 	DBInfo
+	Health DBHealth `json:"health"`
+}
+
+type DBHealth struct {
+	Status    string     `json:"status"` //unknown, up, down, degraded
+	Error     string     `json:"error,omitempty"`
+	Latency   int64      `json:"latency"` //мс
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
 }
 
 type DBInfo struct {
 	Name     string `json:"name" validate:"required"`
-	Host     string `json:"host" validate:"required"`
-	Port     int    `json:"port" validate:"min=1025,max=65535"`
-	DBUser   string `json:"dbUser" validate:"required"`
+	Host     string `json:"host" validate:"required_without_all=Path ConnString ConnStringSet"` //для PostgreSQL можно несколько серверов через запятую
+	Port     int    `json:"port" validate:"required_without_all=Path ConnString ConnStringSet,omitempty,min=1025,max=65535"`
+	DBUser   string `json:"dbUser" validate:"required_without_all=Path ConnString ConnStringSet"`
 	Password string `json:"password"`
-	DBName   string `json:"dbName" validate:"required"`
-	Driver   string `json:"driver" validate:"oneof=PostgreSQL"`
+	DBName   string `json:"dbName" validate:"required_without_all=Path ConnString ConnStringSet"`
+	Driver   string `json:"driver" validate:"oneof=PostgreSQL MySQL SQLite"`
+	Path     string `json:"path,omitempty" validate:"required_if=Driver SQLite"` //файл SQLite на сервере
+	ReadOnly bool   `json:"readOnly,omitempty"`
+
+	SSLMode     string `json:"sslMode,omitempty" validate:"omitempty,oneof=disable require verify-ca verify-full"`
+	SSLRootCert string `json:"sslRootCert,omitempty"` //сертификаты и ключ в формате PEM
+	SSLCert     string `json:"sslCert,omitempty" validate:"required_with=SSLKey"`
+	SSLKey      string `json:"sslKey,omitempty"`    //только для записи; пустое при редактировании - оставить прежний
+	SSLKeySet   bool   `json:"sslKeySet,omitempty"` //в ответе вместо SSLKey
+
+	SSH *DBSSH `json:"ssh,omitempty" validate:"excluded_if=Driver SQLite"`
+
+	Params        map[string]string `json:"params,omitempty" validate:"excluded_with=ConnString ConnStringSet,omitempty,dive,keys,required,endkeys"` //имена проверяются при подключении
+	ConnString    string            `json:"connString,omitempty" validate:"excluded_if=Driver SQLite"`                                               //вместо Host, Port, DBUser, Password, DBName и SSL; только для записи
+	ConnStringSet bool              `json:"connStringSet,omitempty"`                                                                                 //в ответе вместо ConnString; в запросе без ConnString и Host - оставить прежнюю
+
+	Pool *DBPool `json:"pool,omitempty"`
+
+	FunctionList []string `json:"functionList,omitempty" validate:"omitempty,dive,required"`
+	SchemaList   []string `json:"schemaList,omitempty" validate:"omitempty,dive,required"`
+	AllowWrite   bool     `json:"allowWrite"`
+}
+
+type DBSSH struct {
+	Host            string `json:"host" validate:"required"`
+	Port            int    `json:"port" validate:"min=1,max=65535"`
+	User            string `json:"user" validate:"required"`
+	Password        string `json:"password,omitempty"`   //как и PrivateKey с Passphrase, только для записи; новые пароль или ключ заменяют оба прежних
+	PrivateKey      string `json:"privateKey,omitempty"` //PEM
+	Passphrase      string `json:"passphrase,omitempty"`
+	PasswordSet     bool   `json:"passwordSet,omitempty"`                                                //в ответе вместо Password
+	PrivateKeySet   bool   `json:"privateKeySet,omitempty"`                                              //в ответе вместо PrivateKey и Passphrase
+	KnownHosts      string `json:"knownHosts,omitempty" validate:"required_unless=InsecureHostKey true"` //строки в формате known_hosts
+	InsecureHostKey bool   `json:"insecureHostKey,omitempty"`
+}
+
+// DBPool - настройки пула соединений; 0 - значение по умолчанию.
+type DBPool struct {
+	MaxOpen     int   `json:"maxOpen,omitempty" validate:"min=0"`
+	MaxIdle     int   `json:"maxIdle,omitempty" validate:"min=0"`
+	MaxLifetime int64 `json:"maxLifetime,omitempty" validate:"min=0"` //мс
+	MaxIdleTime int64 `json:"maxIdleTime,omitempty" validate:"min=0"` //мс
+}
+
+type DBStats struct {
+	MaxOpen           int   `json:"maxOpen"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"inUse"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"waitCount"`
+	WaitDuration      int64 `json:"waitDuration"` //мс
+	MaxIdleClosed     int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64 `json:"maxLifetimeClosed"`
+}
+
+type DBDiagnosis struct {
+	OK       bool          `json:"ok"`
+	StepList []DBStep      `json:"stepList"`
+	Server   *DBServerInfo `json:"server,omitempty"`
+}
+
+type DBStep struct {
+	Name     string `json:"name"` //config, dns, tcp, ssh, connect, auth, database, info
+	Target   string `json:"target,omitempty"`
+	Status   string `json:"status"` //ok, failed, skipped
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"` //мс
+}
+
+type DBServerInfo struct {
+	Version    string   `json:"version"`
+	User       string   `json:"user,omitempty"`
+	Privileges []string `json:"privileges,omitempty"`
 }
 
 type DBfk struct {
+	Schema     string `json:"schema,omitempty"`
 	TableName  string `json:"tableName"`
 	ColumnName string `json:"columnName"`
 }
@@ -26,14 +110,69 @@ type DBColumn struct {
 	IsRequired bool   `json:"isRequired"`
 	IsPK       bool   `json:"isPK"`
 	FK         *DBfk  `json:"fk,omitempty"`
+
+	Default     string   `json:"default,omitempty"`
+	IsIdentity  bool     `json:"isIdentity"`
+	IsSerial    bool     `json:"isSerial"`
+	IsGenerated bool     `json:"isGenerated"`
+	Generated   string   `json:"generated,omitempty"`
+	MaxLength   *int     `json:"maxLength,omitempty"`
+	Precision   *int     `json:"precision,omitempty"`
+	Scale       *int     `json:"scale,omitempty"`
+	ElementType string   `json:"elementType,omitempty"`
+	EnumList    []string `json:"enumList,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
 }
 
 type DBTable struct {
+	Schema     string     `json:"schema,omitempty"`
 	Name       string     `json:"name"`
+	Kind       string     `json:"kind"` //table, view, materialized view, foreign table, partitioned table
+	Definition string     `json:"definition,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
 	ColumnList []DBColumn `json:"columnList"`
+	IndexList  []DBIndex  `json:"indexList"`
+	UniqueList []DBUnique `json:"uniqueList"`
+	CheckList  []DBCheck  `json:"checkList"`
+	FKList     []DBFKey   `json:"fkList"`
+}
+
+type DBFKey struct {
+	Name          string   `json:"name,omitempty"`
+	ColumnList    []string `json:"columnList"`
+	RefSchema     string   `json:"refSchema,omitempty"`
+	RefTable      string   `json:"refTable"`
+	RefColumnList []string `json:"refColumnList"` //попарно с ColumnList
+	OnDelete      string   `json:"onDelete"`
+	OnUpdate      string   `json:"onUpdate"`
+}
+
+type DBIndex struct {
+	Name       string   `json:"name"`
+	ColumnList []string `json:"columnList"` //колонки или выражения
+	IsUnique   bool     `json:"isUnique"`
+	Method     string   `json:"method"`
+	Predicate  string   `json:"predicate,omitempty"` //условие частичного индекса
+}
+
+type DBUnique struct {
+	Name       string   `json:"name"`
+	ColumnList []string `json:"columnList"`
+}
+
+type DBCheck struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+type DBSignature struct {
+	ArgTypeList []string `json:"argTypeList"`
+	ReturnType  string   `json:"returnType"`
 }
 
 type DBFunction struct {
-	Name     string   `json:"name"`
-	TypeList []string `json:"typeList"`
+	Name          string        `json:"name"`
+	Kind          string        `json:"kind"`
+	TypeList      []string      `json:"typeList"`
+	SignatureList []DBSignature `json:"signatureList"`
 }
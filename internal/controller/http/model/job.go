@@ -0,0 +1,32 @@
+package model
+
+import (
+	"datapoint/internal/model/querymodel"
+	"time"
+)
+
+type Job struct {
+	ID         string              `json:"id"`
+	DBID       string              `json:"databaseId"`
+	Info       querymodel.Document `json:"info"`
+	Status     string              `json:"status"`
+	Error      string              `json:"error,omitempty"`
+	RowCount   int64               `json:"rowCount"`
+	Truncated  bool                `json:"truncated,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	StartedAt  *time.Time          `json:"startedAt,omitempty"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+	ExpiresAt  *time.Time          `json:"expiresAt,omitempty"`
+}
+
+type PageFilter struct {
+	Limit  uint64 `query:"limit" validate:"max=10000"`
+	Offset uint64 `query:"offset"`
+}
+
+type JobPage struct {
+	Data     []map[string]any `json:"data"`
+	RowCount int64            `json:"rowCount"`
+	Limit    uint64           `json:"limit"`
+	Offset   uint64           `json:"offset"`
+}
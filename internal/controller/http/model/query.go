@@ -0,0 +1,62 @@
+package model
+
+import (
+	"datapoint/internal/model/querymodel"
+	"time"
+)
+
+type QueryResult struct {
+	Columns   []ResultColumn   `json:"columns"`
+	Data      []map[string]any `json:"data"`
+	RowCount  int64            `json:"rowCount"`
+	Truncated bool             `json:"truncated,omitempty"`
+	Sampled   bool             `json:"sampled,omitempty"`
+}
+
+type ResultColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type HistoryFilter struct {
+	DBID   string `query:"database" validate:"omitempty,uuid"`
+	Type   string `query:"type" validate:"omitempty,oneof=select insert update delete raw"`
+	Failed *bool  `query:"failed"`
+	From   string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To     string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Limit  uint64 `query:"limit" validate:"max=1000"`
+	Offset uint64 `query:"offset"`
+}
+
+type HistoryEntry struct {
+	ID        string               `json:"id"`
+	DBID      string               `json:"databaseId"`
+	Info      *querymodel.Document `json:"info,omitempty"` //нет у произвольного SQL из консоли
+	Query     string               `json:"query"`
+	Args      []any                `json:"args"`
+	Duration  int64                `json:"duration"` //мс
+	RowCount  int64                `json:"rowCount"`
+	Error     string               `json:"error,omitempty"`
+	Caller    string               `json:"caller,omitempty"`
+	Redacted  bool                 `json:"redacted"`
+	CreatedAt time.Time            `json:"createdAt"`
+}
+
+type Running struct {
+	ID        string               `json:"id"`
+	DBID      string               `json:"databaseId"`
+	Info      *querymodel.Document `json:"info,omitempty"`
+	Query     string               `json:"query,omitempty"` //произвольный SQL из консоли
+	Redacted  bool                 `json:"redacted"`
+	StartedAt time.Time            `json:"startedAt"`
+}
+
+type SQLRequest struct {
+	SQL string `json:"sql" validate:"required"`
+}
+
+type ParseError struct {
+	Position    int    `json:"position"` //номер символа с единицы
+	Message     string `json:"message"`
+	Unsupported bool   `json:"unsupported"`
+}
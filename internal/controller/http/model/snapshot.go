@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+type Snapshot struct {
+	Version   int       `json:"version"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"createdAt"`
+	TableList []DBTable `json:"tableList,omitempty"`
+}
+
+type SnapshotDiffFilter struct {
+	From int `query:"from" validate:"required,min=1"`
+	To   int `query:"to" validate:"required,min=1"`
+}
+
+type SnapshotDiff struct {
+	From          int                 `json:"from"`
+	To            int                 `json:"to"`
+	AddedTables   []string            `json:"addedTables,omitempty"`
+	RemovedTables []string            `json:"removedTables,omitempty"`
+	TableList     []SnapshotTableDiff `json:"tableList"`
+}
+
+type SnapshotTableDiff struct {
+	Schema         string               `json:"schema,omitempty"`
+	Name           string               `json:"name"`
+	AddedColumns   []string             `json:"addedColumns,omitempty"`
+	RemovedColumns []string             `json:"removedColumns,omitempty"`
+	ColumnList     []SnapshotColumnDiff `json:"columnList,omitempty"` //изменения типа
+	AddedFKs       []DBFKey             `json:"addedFKs,omitempty"`
+	RemovedFKs     []DBFKey             `json:"removedFKs,omitempty"`
+}
+
+type SnapshotColumnDiff struct {
+	Name     string `json:"name"`
+	FromType string `json:"fromType"`
+	ToType   string `json:"toType"`
+}
@@ -0,0 +1,208 @@
+package querycontroller
+
+import (
+	"context"
+	"datapoint/internal/controller/http/converter"
+	"datapoint/internal/controller/http/model"
+	"datapoint/internal/model/historymodel"
+	"datapoint/internal/model/querymodel"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+type Service interface {
+	Execute(ctx context.Context, info querymodel.Info, id, qid string) (querymodel.QueryResult, error)
+	History(ctx context.Context, f historymodel.Filter) ([]*historymodel.Entry, error)
+	Rerun(ctx context.Context, hid, qid string) (querymodel.QueryResult, error)
+	RunningList() []querymodel.Running
+	Cancel(ctx context.Context, qid string) error
+	Import(ctx context.Context, sql, id string) (querymodel.Info, error)
+	ExecuteRaw(ctx context.Context, sql, id, qid string) (querymodel.QueryResult, error)
+}
+
+// QueryIDHeader содержит идентификатор запроса, по которому его можно отменить через /queries/:qid.
+// Сразу идентификатор возвращает задание (/job/:id): идентификатор задания совпадает с идентификатором
+// запроса. У синхронного запроса сгенерированный идентификатор приходит вместе с результатом, поэтому
+// его можно передать в этом заголовке заранее или найти в /queries/running.
+const QueryIDHeader = "X-Query-ID"
+
+type controller struct {
+	s Service
+	v *validator.Validate
+}
+
+func (c *controller) execute(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var qid string
+	if qid, err = c.queryID(ctx); err != nil {
+		return err
+	}
+
+	var body querymodel.Info
+	if body, err = querymodel.Decode(ctx.Body()); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var result querymodel.QueryResult
+	if result, err = c.s.Execute(ctx.Context(), body, id, qid); err != nil {
+		if errors.Is(err, querymodel.ErrInvalid) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(converter.ToQueryResult(result))
+}
+
+func (c *controller) executeRaw(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var qid string
+	if qid, err = c.queryID(ctx); err != nil {
+		return err
+	}
+
+	var body model.SQLRequest
+	if err = ctx.Bind().JSON(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var result querymodel.QueryResult
+	if result, err = c.s.ExecuteRaw(ctx.Context(), body.SQL, id, qid); err != nil {
+		var parseErr *querymodel.ParseError
+		if errors.As(err, &parseErr) {
+			return ctx.
+				Status(fiber.StatusUnprocessableEntity).
+				JSON(converter.ToParseError(parseErr))
+		}
+		return err
+	}
+
+	return ctx.JSON(converter.ToQueryResult(result))
+}
+
+func (c *controller) history(ctx fiber.Ctx) error {
+	var filter model.HistoryFilter
+
+	err := ctx.Bind().Query(&filter)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var list []*historymodel.Entry
+	if list, err = c.s.History(ctx.Context(), converter.FromHistoryFilter(filter)); err != nil {
+		return err
+	}
+
+	return ctx.JSON(converter.ToHistoryEntryList(list))
+}
+
+func (c *controller) rerun(ctx fiber.Ctx) error {
+	hid := ctx.Params("hid")
+	err := c.v.Var(hid, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var qid string
+	if qid, err = c.queryID(ctx); err != nil {
+		return err
+	}
+
+	var result querymodel.QueryResult
+	if result, err = c.s.Rerun(ctx.Context(), hid, qid); err != nil {
+		switch {
+		case errors.Is(err, historymodel.ErrRedacted):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case errors.Is(err, querymodel.ErrInvalid):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(converter.ToQueryResult(result))
+}
+
+// queryID берёт идентификатор из QueryIDHeader или генерирует его и возвращает в том же заголовке ответа.
+func (c *controller) queryID(ctx fiber.Ctx) (string, error) {
+	qid := ctx.Get(QueryIDHeader)
+	if len(qid) == 0 {
+		qid = uuid.NewString()
+	} else if err := c.v.Var(qid, "uuid"); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	ctx.Set(QueryIDHeader, qid)
+	return qid, nil
+}
+
+func (c *controller) runningList(ctx fiber.Ctx) error {
+	return ctx.JSON(converter.ToRunningList(c.s.RunningList()))
+}
+
+func (c *controller) cancel(ctx fiber.Ctx) error {
+	qid := ctx.Params("qid")
+	err := c.v.Var(qid, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.s.Cancel(ctx.Context(), qid)
+}
+
+func (c *controller) importSQL(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var body model.SQLRequest
+	if err = ctx.Bind().JSON(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var info querymodel.Info
+	if info, err = c.s.Import(ctx.Context(), body.SQL, id); err != nil {
+		var parseErr *querymodel.ParseError
+		if errors.As(err, &parseErr) {
+			return ctx.
+				Status(fiber.StatusUnprocessableEntity).
+				JSON(converter.ToParseError(parseErr))
+		}
+		return err
+	}
+
+	return ctx.JSON(querymodel.NewDocument(info))
+}
+
+func (c *controller) schema(ctx fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, "application/schema+json")
+	return ctx.Send(querymodel.Schema)
+}
+
+func New(r fiber.Router, s Service, v *validator.Validate) {
+	c := controller{s: s, v: v}
+	g := r.Group("/query")
+	g.Get("/schema", c.schema)
+	g.Get("/history", c.history)
+	g.Post("/history/:hid", c.rerun)
+	g.Post("/:id", c.execute)
+	g.Post("/:id/import", c.importSQL)
+	g.Post("/:id/raw", c.executeRaw)
+
+	running := r.Group("/queries")
+	running.Get("/running", c.runningList)
+	running.Delete("/:qid", c.cancel)
+}
@@ -0,0 +1,119 @@
+package snapshotcontroller
+
+import (
+	"context"
+	"datapoint/internal/controller/http/converter"
+	"datapoint/internal/controller/http/model"
+	"datapoint/internal/model/snapshotmodel"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+	"strconv"
+)
+
+type Service interface {
+	Take(ctx context.Context, id string) (*snapshotmodel.Snapshot, bool, error)
+	GetList(ctx context.Context, id string) ([]*snapshotmodel.Snapshot, error)
+	GetByVersion(ctx context.Context, id string, version int) (*snapshotmodel.Snapshot, error)
+	Diff(ctx context.Context, id string, from, to int) (snapshotmodel.Diff, error)
+}
+
+type controller struct {
+	s Service
+	v *validator.Validate
+}
+
+func (c *controller) take(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	snapshot, created, err := c.s.Take(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	//схема не изменилась - возвращается последний снимок
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+
+	return ctx.
+		Status(status).
+		JSON(converter.ToSnapshot(snapshot))
+}
+
+func (c *controller) getList(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var list []*snapshotmodel.Snapshot
+	if list, err = c.s.GetList(ctx.Context(), id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(converter.ToSnapshotList(list))
+}
+
+func (c *controller) getByVersion(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var version int
+	if version, err = strconv.Atoi(ctx.Params("version")); err != nil || version < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "некорректная версия снимка")
+	}
+
+	var snapshot *snapshotmodel.Snapshot
+	if snapshot, err = c.s.GetByVersion(ctx.Context(), id, version); err != nil {
+		return notFound(err)
+	}
+
+	return ctx.JSON(converter.ToSnapshot(snapshot))
+}
+
+func (c *controller) diff(ctx fiber.Ctx) error {
+	id := ctx.Params("id")
+	err := c.v.Var(id, "uuid")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var filter model.SnapshotDiffFilter
+	if err = ctx.Bind().Query(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var diff snapshotmodel.Diff
+	if diff, err = c.s.Diff(ctx.Context(), id, filter.From, filter.To); err != nil {
+		return notFound(err)
+	}
+
+	return ctx.JSON(converter.ToSnapshotDiff(diff))
+}
+
+// notFound отвечает 404, только если снимка нет; остальные ошибки остаются внутренними.
+func notFound(err error) error {
+	if errors.Is(err, snapshotmodel.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return err
+}
+
+func New(r fiber.Router, s Service, v *validator.Validate) {
+	c := controller{s: s, v: v}
+	g := r.Group("/database/:id/snapshot")
+	g.Get("/", c.getList)
+	g.Post("/", c.take)
+	g.Get("/diff", c.diff)
+	g.Get("/:version", c.getByVersion)
+}
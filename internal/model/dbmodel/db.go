@@ -4,58 +4,109 @@ import (
 	"context"
 	"database/sql"
 	"datapoint/pkg/database"
+	"encoding/json"
+	"errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"sync"
+	"time"
 )
 
 type DB struct {
-	ID   string
-	Info Info
-	db   *database.Database
+	ID      string
+	Info    Info //после добавления в сервис меняется через SetInfo и читается через GetInfo
+	db      *database.Database
+	dialect Dialect
+
+	openMu   sync.Mutex //подключение из запроса, фоновой проверки и SetInfo; защищает Info, db, dialect и closed
+	closed   bool
+	healthMu sync.RWMutex
+	health   Health
 }
 
 func New(info Info) (*DB, error) {
-	driverName, dataSourceName := info.Config.Parse()
-	db, err := database.New(driverName, dataSourceName)
+	db, dialect, err := connect(context.Background(), info)
 	if err != nil {
 		return nil, err
 	}
-	return &DB{ID: uuid.NewString(), Info: info, db: db}, nil
+	return &DB{ID: uuid.NewString(), Info: info, db: db, dialect: dialect}, nil
 }
 
+// Open подключается, если подключения ещё нет.
 func (db *DB) Open() error {
-	driverName, dataSourceName := db.Info.Config.Parse()
-	d, err := database.New(driverName, dataSourceName)
-	db.db = d
+	_, _, err := db.conn(context.Background())
 	return err
 }
 
+// connect подключается по настройкам info; ctx ограничивает время подключения.
+func connect(ctx context.Context, info Info) (*database.Database, Dialect, error) {
+	dialect, dsn, err := info.Config.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tunnel, err := info.Config.Tunnel()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d, err := database.NewContext(ctx, dialect.Driver(), dsn, tunnel, info.Config.Pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, dialect, nil
+}
+
+// SetInfo подключается по новым настройкам и заменяет ими прежнее подключение, если оно было.
+// Прежнее подключение закрывается после того, как завершатся начатые на нём запросы.
 func (db *DB) SetInfo(info Info) error {
-	driverName, dataSourceName := info.Config.Parse()
-	if err := db.db.Open(driverName, dataSourceName); err != nil {
+	d, dialect, err := connect(context.Background(), info)
+	if err != nil {
 		return err
 	}
-	db.Info = info
+
+	db.openMu.Lock()
+	old := db.db
+	db.Info, db.db, db.dialect = info, d, dialect
+	db.openMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
 	return nil
 }
 
+// GetInfo возвращает текущие настройки базы данных.
+func (db *DB) GetInfo() Info {
+	db.openMu.Lock()
+	defer db.openMu.Unlock()
+
+	return db.Info
+}
+
 func (db *DB) Close() {
-	db.db.Close()
+	db.openMu.Lock()
+	defer db.openMu.Unlock()
+
+	db.closed = true
+	if db.db != nil {
+		db.db.Close()
+	}
 }
 
 type Info struct {
-	Name   string
-	Config Config
+	Name         string
+	Config       Config
+	FunctionList []string //nil == DefaultFunctionList
+	SchemaList   []string //nil == схемы по умолчанию для диалекта
+	AllowWrite   bool     //разрешает изменяющий SQL в консоли
 }
 
 const (
 	PostgreSQL = "PostgreSQL"
-)
-
-var (
-	drivers = map[string]string{PostgreSQL: database.Postgres}
-	schemas = map[string]string{PostgreSQL: "postgresql"}
+	MySQL      = "MySQL" //также MariaDB
+	SQLite     = "SQLite"
 )
 
 type Config struct {
@@ -65,40 +116,223 @@ type Config struct {
 	Password string
 	Name     string
 	Driver   string
+	Path     string //файл SQLite вместо Host, Port, User, Password и Name
+	ReadOnly bool   //открыть файл SQLite только для чтения
+
+	SSLMode     string //SSLDisable, SSLRequire, SSLVerifyCA, SSLVerifyFull; пустое значение == SSLDisable
+	SSLRootCert string //сертификаты и ключ в формате PEM
+	SSLCert     string
+	SSLKey      string
+
+	SSH *database.SSHConfig //подключение через сервер-посредник; nil - напрямую
+
+	Params     map[string]string //дополнительные параметры строки подключения, допустимые для драйвера
+	ConnString string            //готовая строка подключения вместо остальных полей, кроме Driver и SSH
+
+	Pool database.PoolConfig
 }
 
-func (c *Config) Parse() (string, string) {
-	return drivers[c.Driver],
-		fmt.Sprintf(
-			"%s://%s:%s@%s:%d/%s?sslmode=disable",
-			schemas[c.Driver], c.User, c.Password, c.Host, c.Port, c.Name,
-		)
+// KeepSecrets подставляет закрытые ключи и пароли из old вместо пустых: клиенту они не возвращаются,
+// поэтому при редактировании пустое значение означает "оставить прежнее". Готовая строка подключения
+// обычно содержит пароль и сохраняется, если не указаны ни она, ни сервер, ни файл.
+func (c *Config) KeepSecrets(old Config) {
+	if len(c.ConnString) == 0 && len(c.Host) == 0 && len(c.Path) == 0 {
+		c.ConnString = old.ConnString
+	}
+
+	if len(c.SSLCert) != 0 && len(c.SSLKey) == 0 {
+		c.SSLKey = old.SSLKey
+	}
+
+	if c.SSH == nil || old.SSH == nil {
+		return
+	}
+
+	//новый пароль или ключ меняет способ входа, и прежние секреты не подставляются: иначе пароль
+	//нельзя было бы убрать при переходе на ключ
+	if len(c.SSH.Password) != 0 || len(c.SSH.PrivateKey) != 0 {
+		return
+	}
+
+	ssh := *c.SSH
+	ssh.Password = old.SSH.Password
+	ssh.PrivateKey, ssh.Passphrase = old.SSH.PrivateKey, old.SSH.Passphrase
+	c.SSH = &ssh
+}
+
+// Parse возвращает диалект драйвера и строку подключения.
+func (c *Config) Parse() (Dialect, string, error) {
+	dialect, ok := DialectOf(c.Driver)
+	if !ok {
+		return nil, "", fmt.Errorf("неизвестный драйвер базы данных: %s", c.Driver)
+	}
+
+	if len(c.ConnString) != 0 {
+		//режим только для чтения SQLite задаётся в строке подключения
+		if c.Driver == SQLite {
+			return nil, "", errors.New("для SQLite указывается путь к файлу, а не строка подключения")
+		}
+		if len(c.Params) != 0 {
+			return nil, "", errors.New("дополнительные параметры нельзя указать вместе с готовой строкой подключения")
+		}
+		if c.Driver == MySQL {
+			if err := mysqlCheckConnString(c.ConnString); err != nil {
+				return nil, "", err
+			}
+		}
+		return dialect, c.ConnString, nil
+	}
+
+	dsn, err := dialect.DSN(*c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return dialect, dsn, nil
+}
+
+// Tunnel открывает SSH-туннель, если он настроен.
+func (c *Config) Tunnel() (*database.Tunnel, error) {
+	if c.SSH == nil {
+		return nil, nil
+	}
+
+	tunnel, err := database.NewTunnel(*c.SSH)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть SSH-туннель: %s", err)
+	}
+	return tunnel, nil
 }
 
 func (db *DB) Check() error {
-	if db.db == nil {
-		return db.Open()
+	_, _, err := db.conn(context.Background())
+	return err
+}
+
+// conn подключается, если подключения ещё нет, и возвращает текущие подключение и диалект:
+// SetInfo может заменить их в любой момент. Подключение идёт без openMu, чтобы недоступный
+// сервер не задерживал GetInfo и остальные вызовы дольше, чем позволяет ctx.
+func (db *DB) conn(ctx context.Context) (*database.Database, Dialect, error) {
+	db.openMu.Lock()
+	d, dialect, info := db.db, db.dialect, db.Info
+	db.openMu.Unlock()
+
+	if d != nil {
+		return d, dialect, nil
 	}
-	return nil
+
+	d, dialect, err := connect(ctx, info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db.openMu.Lock()
+	defer db.openMu.Unlock()
+
+	if db.closed {
+		d.Close()
+		return nil, nil, errors.New("база данных удалена")
+	}
+
+	//пока шло подключение, другой вызов или SetInfo уже подключились
+	if db.db != nil {
+		d.Close()
+		return db.db, db.dialect, nil
+	}
+
+	db.db, db.dialect = d, dialect
+	return d, dialect, nil
+}
+
+// Stats возвращает состояние пула соединений.
+func (db *DB) Stats() (sql.DBStats, error) {
+	d, _, err := db.conn(context.Background())
+	if err != nil {
+		return sql.DBStats{}, err
+	}
+
+	return d.Stats(), nil
+}
+
+// Dialect возвращает диалект базы данных; до подключения - диалект из настроек или nil.
+func (db *DB) Dialect() Dialect {
+	db.openMu.Lock()
+	defer db.openMu.Unlock()
+
+	if db.dialect != nil {
+		return db.dialect
+	}
+	dialect, _ := DialectOf(db.Info.Config.Driver)
+	return dialect
 }
 
 func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	if err := db.Check(); err != nil {
+	d, _, err := db.conn(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	return db.db.QueryContext(ctx, query, args...)
+	return d.QueryContext(ctx, query, args...)
 }
 
 func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	if err := db.Check(); err != nil {
+	d, _, err := db.conn(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	return db.db.ExecContext(ctx, query, args...)
+	return d.ExecContext(ctx, query, args...)
+}
+
+// Conn возвращает выделенное соединение и идентификатор его серверного процесса
+// (0, если драйвер не позволяет отменить запрос на стороне сервера).
+func (db *DB) Conn(ctx context.Context) (*sql.Conn, int64, error) {
+	d, dialect, err := db.conn(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var pid int64
+	if pid, err = dialect.BackendID(ctx, conn); err != nil {
+		_ = conn.Close()
+		return nil, 0, err
+	}
+
+	return conn, pid, nil
+}
+
+func (db *DB) CancelBackend(ctx context.Context, pid int64) error {
+	d, dialect, err := db.conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if pid == 0 {
+		return nil
+	}
+
+	return dialect.CancelBackend(ctx, d, pid)
+}
+
+// BeginConsole начинает транзакцию для произвольного SQL на выделенном соединении: только для чтения,
+// если база данных не разрешает запись, и с ограничением времени выполнения, если диалект его поддерживает.
+func (db *DB) BeginConsole(ctx context.Context, conn *sql.Conn, timeout time.Duration) (*sql.Tx, error) {
+	return db.Dialect().BeginConsole(ctx, conn, !db.GetInfo().AllowWrite, timeout)
+}
+
+// ResetSession сбрасывает состояние сеанса, которое произвольный SQL мог изменить,
+// прежде чем соединение вернётся в пул.
+func (db *DB) ResetSession(ctx context.Context, conn *sql.Conn) error {
+	return db.Dialect().ResetSession(ctx, conn)
 }
 
 type FK struct {
+	Schema     string
 	TableName  string
 	ColumnName string
 }
@@ -108,147 +342,168 @@ type Column struct {
 	Type       string
 	IsRequired bool
 	IsPK       bool
-	FK         *FK
+	FK         *FK //первый внешний ключ из одной этой колонки; все внешние ключи - в Table.FKList
+
+	Default     string //выражение по умолчанию; пустое значение - нет
+	IsIdentity  bool   //GENERATED AS IDENTITY, AUTO_INCREMENT или INTEGER PRIMARY KEY в SQLite
+	IsSerial    bool   //значение по умолчанию из последовательности (serial в PostgreSQL)
+	IsGenerated bool   //вычисляемая колонка, в неё нельзя писать
+	Generated   string //выражение вычисляемой колонки, если база данных его сообщает
+	MaxLength   *int   //для строк ограниченной длины
+	Precision   *int   //для numeric
+	Scale       *int   //для numeric
+	ElementType string //тип элемента массива
+	EnumList    []string
+	Comment     string
 }
 
+// Виды таблиц.
+const (
+	KindTable            = "table"
+	KindView             = "view"
+	KindMaterializedView = "materialized view"
+	KindForeignTable     = "foreign table"
+	KindPartitionedTable = "partitioned table"
+)
+
 type Table struct {
+	Schema     string //пустое значение, если база данных без схем
 	Name       string
+	Kind       string
+	Definition string //запрос представления; пустое значение для таблиц
+	Comment    string
 	ColumnList []*Column
+	IndexList  []*Index
+	UniqueList []*Constraint
+	CheckList  []*Constraint
+	FKList     []*ForeignKey
 }
 
-func (db *DB) tableList(ctx context.Context, where sq.Sqlizer) ([]*Table, error) {
-	err := db.Check()
+// ReadOnly сообщает, что таблица - только источник данных: представления в построителе не изменяются.
+func (t *Table) ReadOnly() bool {
+	return t.Kind == KindView || t.Kind == KindMaterializedView
+}
+
+func (db *DB) tableList(ctx context.Context, name string) ([]*Table, error) {
+	d, dialect, err := db.conn(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var rows *sql.Rows
-	if rows, err = db.db.B.Select(
-		"c.table_name",
-		"c.column_name",
-		"c.data_type",
-		"c.is_nullable = 'NO' AND c.column_default IS NULL",
-		"tc.constraint_type",
-		"kcu2.table_name",
-		"kcu2.column_name",
-	).From("information_schema.columns c").
-		LeftJoin("information_schema.key_column_usage kcu USING (table_name, column_name)").
-		LeftJoin("information_schema.table_constraints tc USING (constraint_name)").
-		LeftJoin("information_schema.referential_constraints rc USING (constraint_name)").
-		LeftJoin("information_schema.key_column_usage kcu2 ON rc.unique_constraint_name = kcu2.constraint_name").
-		Where("c.table_schema = 'public'").
-		Where(where).
-		OrderBy("c.table_name", "c.ordinal_position").
-		QueryContext(ctx); err != nil {
+	tableList, err := dialect.TableList(ctx, builder(d, dialect), db.GetInfo().SchemaList, name)
+	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
 
+	for _, t := range tableList {
+		for _, c := range t.ColumnList {
+			c.Type = dialect.NormalizeType(c.Type)
+			c.ElementType = dialect.NormalizeType(c.ElementType)
+		}
+		t.linkFK()
+	}
+
+	return tableList, nil
+}
+
+// scanTableList собирает таблицы из строк, по строке на колонку, упорядоченных по таблице и колонке:
+// схема, таблица, вид, определение, комментарий таблицы, колонка, тип, обязательность, первичный ключ,
+// значение по умолчанию, identity, serial, вычисляемость и выражение, длина, точность, масштаб, тип элемента,
+// значения перечисления в виде, который разбирает enumList, комментарий колонки.
+func scanTableList(rows *sql.Rows, enumList func(string) ([]string, error)) ([]*Table, error) {
 	var (
 		tableList []*Table
 		lastT     *Table
-		lastC     *Column
 	)
 
 	for rows.Next() {
 		var (
-			t                    = new(Table)
-			c                    = new(Column)
-			constraint, fkT, fkC *string
+			t                                                             = new(Table)
+			c                                                             = new(Column)
+			definition, tableComment, def, generated, elem, enum, comment *string
 		)
 
-		if err = rows.Scan(&t.Name, &c.Name, &c.Type, &c.IsRequired, &constraint, &fkT, &fkC); err != nil {
+		if err := rows.Scan(&t.Schema, &t.Name, &t.Kind, &definition, &tableComment,
+			&c.Name, &c.Type, &c.IsRequired, &c.IsPK, &def, &c.IsIdentity, &c.IsSerial, &c.IsGenerated, &generated,
+			&c.MaxLength, &c.Precision, &c.Scale, &elem, &enum, &comment); err != nil {
 			return nil, err
 		}
 
-		if lastT == nil || lastT.Name != t.Name {
-			lastT = t
-			tableList = append(tableList, t)
+		for _, v := range [...]struct {
+			src *string
+			dst *string
+		}{
+			{definition, &t.Definition},
+			{tableComment, &t.Comment},
+			{def, &c.Default},
+			{generated, &c.Generated},
+			{elem, &c.ElementType},
+			{comment, &c.Comment},
+		} {
+			if v.src != nil {
+				*v.dst = *v.src
+			}
 		}
 
-		if lastC == nil || lastC.Name != c.Name {
-			lastC = c
-			lastT.ColumnList = append(lastT.ColumnList, c)
+		if enum != nil {
+			var err error
+			if c.EnumList, err = enumList(*enum); err != nil {
+				return nil, err
+			}
 		}
 
-		if constraint != nil && *constraint == "PRIMARY KEY" {
-			lastC.IsPK = true
-		} else if constraint != nil && *constraint == "FOREIGN KEY" && fkT != nil && fkC != nil {
-			lastC.FK = &FK{TableName: *fkT, ColumnName: *fkC}
+		if lastT == nil || lastT.Schema != t.Schema || lastT.Name != t.Name {
+			lastT = t
+			tableList = append(tableList, t)
 		}
+
+		lastT.ColumnList = append(lastT.ColumnList, c)
 	}
 
-	return tableList, nil
+	return tableList, rows.Err()
+}
+
+// jsonList разбирает значения перечисления, собранные в JSON-массив.
+func jsonList(s string) ([]string, error) {
+	var list []string
+	err := json.Unmarshal([]byte(s), &list)
+	return list, err
 }
 
 func (db *DB) TableList(ctx context.Context) ([]*Table, error) {
-	return db.tableList(ctx, nil)
+	return db.tableList(ctx, "")
 }
 
-func (db *DB) TableByName(ctx context.Context, name string) (*Table, error) {
-	tableList, err := db.tableList(ctx, sq.Eq{"c.table_name": name})
+func (db *DB) SchemaList(ctx context.Context) ([]string, error) {
+	d, dialect, err := db.conn(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(tableList) == 0 {
-		return nil, fmt.Errorf("таблицы с именем %s не существует", name)
-	}
-
-	return tableList[0], nil
+	return dialect.SchemaList(ctx, builder(d, dialect))
 }
 
-type Function struct {
-	Name     string
-	TypeList []string //nil == any
-}
-
-func (db *DB) FunctionList(ctx context.Context) ([]*Function, error) {
-	err := db.Check()
+// TableByName ищет таблицу по имени в схемах базы данных по порядку.
+func (db *DB) TableByName(ctx context.Context, name string) (*Table, error) {
+	tableList, err := db.tableList(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
-	var rows *sql.Rows
-	if rows, err = db.db.B.
-		Select("routine_name", "p.data_type").
-		From("information_schema.routines").
-		Join("information_schema.parameters p USING (specific_name)").
-		Where(sq.Eq{"routine_name": []string{"avg", "count", "max", "min", "sum"}}).
-		OrderBy("routine_name").
-		QueryContext(ctx); err != nil {
-		return nil, err
+	if len(tableList) == 0 {
+		return nil, fmt.Errorf("таблицы с именем %s не существует", name)
 	}
-	defer func() { _ = rows.Close() }()
-
-	const anyType = "\"any\""
-
-	var (
-		functionList []*Function
-		lastF        *Function
-	)
-
-	for rows.Next() {
-		var f, t string
-
-		if err = rows.Scan(&f, &t); err != nil {
-			return nil, err
-		}
 
-		if lastF == nil || lastF.Name != f {
-			newF := &Function{Name: f}
-			lastF = newF
-			functionList = append(functionList, newF)
-		}
+	return tableList[0], nil
+}
 
-		if t != anyType {
-			lastF.TypeList = append(lastF.TypeList, t)
-		}
-	}
+func (db *DB) B() sq.StatementBuilderType {
+	db.openMu.Lock()
+	defer db.openMu.Unlock()
 
-	return functionList, nil
+	return builder(db.db, db.dialect)
 }
 
-func (db *DB) B() sq.StatementBuilderType {
-	return db.db.B
+func builder(d *database.Database, dialect Dialect) sq.StatementBuilderType {
+	return d.B.PlaceholderFormat(dialect.Placeholder())
 }
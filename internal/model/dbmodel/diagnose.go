@@ -0,0 +1,224 @@
+package dbmodel
+
+import (
+	"context"
+	"datapoint/pkg/database"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Этапы проверки подключения в порядке выполнения.
+const (
+	StepConfig   = "config"
+	StepDNS      = "dns"
+	StepTCP      = "tcp"
+	StepSSH      = "ssh"
+	StepConnect  = "connect"
+	StepAuth     = "auth"
+	StepDatabase = "database"
+	StepInfo     = "info"
+)
+
+const (
+	StepOK      = "ok"
+	StepFailed  = "failed"
+	StepSkipped = "skipped" //не выполнялся, потому что не пройден предыдущий этап или его не по чему проверить
+)
+
+type Step struct {
+	Name     string
+	Target   string //адрес, к которому относится этап
+	Status   string
+	Error    string
+	Duration time.Duration
+}
+
+type ServerInfo struct {
+	Version    string
+	User       string
+	Privileges []string
+}
+
+type Diagnosis struct {
+	OK       bool
+	StepList []*Step
+	Server   *ServerInfo
+}
+
+// Diagnose пошагово проверяет подключение по настройкам c, ничего не сохраняя. Этапы, которые
+// к подключению не относятся (например, DNS для SQLite), в результат не попадают.
+// DNS и TCP проверяются для каждого сервера из списка; если серверов несколько, проверка
+// продолжается, пока доступен хотя бы один из них.
+func Diagnose(ctx context.Context, c Config) Diagnosis {
+	d := Diagnosis{OK: true}
+
+	dialect, dsn, err := c.Parse()
+	if d.step(StepConfig, "", time.Now(), err) {
+		return d
+	}
+
+	addrList, addrErr := c.addrList()
+	if addrErr != nil {
+		//строку подключения проверит драйвер, а сетевые этапы проверить не по чему
+		for _, name := range [...]string{StepDNS, StepTCP} {
+			d.StepList = append(d.StepList, &Step{Name: name, Status: StepSkipped, Error: addrErr.Error()})
+		}
+	}
+
+	if len(addrList) != 0 && !d.network(ctx, addrList) {
+		rest := []string{StepConnect, StepAuth, StepDatabase, StepInfo}
+		if c.SSH != nil {
+			rest = append([]string{StepSSH}, rest...)
+		}
+		for _, name := range rest {
+			d.StepList = append(d.StepList, &Step{Name: name, Status: StepSkipped})
+		}
+		return d
+	}
+
+	var pending []string
+	if c.SSH != nil {
+		pending = append(pending, StepSSH)
+	}
+	if c.Driver != SQLite {
+		pending = append(pending, StepConnect, StepAuth)
+	}
+	pending = append(pending, StepDatabase, StepInfo)
+
+	var (
+		tunnel *database.Tunnel
+		db     *database.Database
+	)
+	defer func() {
+		if db != nil {
+			db.Close()
+		} else if tunnel != nil {
+			_ = tunnel.Close()
+		}
+	}()
+
+	for i := 0; i < len(pending); i++ {
+		var (
+			name   = pending[i]
+			start  = time.Now()
+			target string
+			err    error
+		)
+
+		switch name {
+		case StepSSH:
+			target = addrList[0]
+			tunnel, err = c.Tunnel()
+		case StepConnect, StepAuth, StepDatabase:
+			if db == nil {
+				//драйвер проходит все три этапа за одно подключение, поэтому время относится к первому из них
+				db, err = database.NewContext(ctx, dialect.Driver(), dsn, tunnel, c.Pool)
+				tunnel = nil //принадлежит подключению или уже закрыт
+
+				if err != nil && c.Driver != SQLite {
+					failed := dialect.ConnError(err)
+					if failed == "" {
+						failed = StepConnect
+					}
+					for name != failed {
+						d.step(name, "", start, nil)
+						i++
+						name = pending[i]
+					}
+				}
+			}
+		case StepInfo:
+			var info ServerInfo
+			if info, err = dialect.ServerInfo(ctx, db); err == nil {
+				d.Server = &info
+			}
+		}
+
+		if d.step(name, target, start, err) {
+			for _, name := range pending[i+1:] {
+				d.StepList = append(d.StepList, &Step{Name: name, Status: StepSkipped})
+			}
+			return d
+		}
+	}
+
+	return d
+}
+
+// network проверяет DNS и TCP для каждого адреса и сообщает, доступен ли хотя бы один.
+func (d *Diagnosis) network(ctx context.Context, addrList []string) bool {
+	reachable := false
+	for _, addr := range addrList {
+		host, _, _ := net.SplitHostPort(addr)
+
+		start := time.Now()
+		_, err := net.DefaultResolver.LookupHost(ctx, host)
+		if d.step(StepDNS, host, start, err) {
+			d.StepList = append(d.StepList, &Step{Name: StepTCP, Target: addr, Status: StepSkipped})
+			continue
+		}
+
+		start = time.Now()
+		var conn net.Conn
+		if conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr); err == nil {
+			_ = conn.Close()
+			reachable = true
+		}
+		d.step(StepTCP, addr, start, err)
+	}
+
+	if reachable {
+		d.OK = true //недоступные серверы из списка пропускаются, как при подключении
+	}
+	return reachable
+}
+
+// addrList возвращает адреса, до которых проверяется сеть: сервер SSH, если он есть (до базы данных
+// подключается уже он), иначе серверы базы данных, в том числе из готовой строки подключения.
+// Для SQLite и подключения через сокет адресов нет.
+func (c *Config) addrList() ([]string, error) {
+	if c.SSH != nil {
+		return []string{net.JoinHostPort(c.SSH.Host, strconv.Itoa(int(c.SSH.Port)))}, nil
+	}
+
+	switch {
+	case c.Driver == SQLite:
+		return nil, nil
+	case len(c.ConnString) == 0:
+		var list []string
+		for _, host := range strings.Split(c.Host, ",") {
+			list = append(list, net.JoinHostPort(host, strconv.Itoa(int(c.Port))))
+		}
+		return list, nil
+	case c.Driver == MySQL:
+		cfg, err := mysql.ParseDSN(c.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось определить адрес сервера из строки подключения: %s", err)
+		}
+		if cfg.Net != "tcp" {
+			return nil, nil
+		}
+		return []string{cfg.Addr}, nil
+	}
+
+	list, err := database.PostgresAddrList(c.ConnString)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось определить адрес сервера из строки подключения: %s", err)
+	}
+	return list, nil
+}
+
+// step записывает результат этапа и сообщает, что он не пройден.
+func (d *Diagnosis) step(name, target string, start time.Time, err error) bool {
+	s := &Step{Name: name, Target: target, Status: StepOK, Duration: time.Since(start)}
+	if err != nil {
+		s.Status, s.Error = StepFailed, err.Error()
+		d.OK = false
+	}
+	d.StepList = append(d.StepList, s)
+	return err != nil
+}
@@ -0,0 +1,111 @@
+package dbmodel_test
+
+import (
+	"context"
+	"database/sql"
+	"datapoint/internal/model/dbmodel"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "diagnose.db")
+
+	file, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	//свободный порт, на котором никто не слушает
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := uint16(l.Addr().(*net.TCPAddr).Port)
+	_ = l.Close()
+
+	//порт, на котором соединения принимаются и сразу закрываются
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = open.Close() }()
+	go func() {
+		for {
+			conn, err := open.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	openPort := open.Addr().(*net.TCPAddr).Port
+
+	tests := [...]struct {
+		config   dbmodel.Config
+		expected string //этапы со статусами
+	}{
+		{
+			config:   dbmodel.Config{Driver: dbmodel.SQLite, Path: path, ReadOnly: true},
+			expected: "config:ok database:ok info:ok",
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.SQLite, Path: filepath.Join(dir, "missing.db"), ReadOnly: true},
+			expected: "config:ok database:failed info:skipped",
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.PostgreSQL, Host: "127.0.0.1", Port: closedPort, User: "user", Name: "db"},
+			expected: "config:ok dns:ok tcp:failed connect:skipped auth:skipped database:skipped info:skipped",
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.PostgreSQL, ConnString: fmt.Sprintf("host=127.0.0.1 port=%d dbname=db", closedPort)},
+			expected: "config:ok dns:ok tcp:failed connect:skipped auth:skipped database:skipped info:skipped",
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.MySQL, ConnString: fmt.Sprintf("user@tcp(127.0.0.1:%d)/db", closedPort)},
+			expected: "config:ok dns:ok tcp:failed connect:skipped auth:skipped database:skipped info:skipped",
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.PostgreSQL,
+				ConnString: fmt.Sprintf("host=127.0.0.1,127.0.0.1 port=%d,%d dbname=db", closedPort, openPort)},
+			expected: "config:ok dns:ok tcp:failed dns:ok tcp:ok connect:failed auth:skipped database:skipped info:skipped",
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.PostgreSQL, ConnString: "host=127.0.0.1,127.0.0.1 port=1,2,3"},
+			expected: "config:ok dns:skipped tcp:skipped connect:failed auth:skipped database:skipped info:skipped",
+		},
+		{
+			config:   dbmodel.Config{Driver: "Oracle"},
+			expected: "config:failed",
+		},
+	}
+
+	for _, test := range tests {
+		d := dbmodel.Diagnose(context.Background(), test.config)
+
+		given := make([]string, 0, len(d.StepList))
+		for _, s := range d.StepList {
+			given = append(given, s.Name+":"+s.Status)
+		}
+
+		if strings.Join(given, " ") != test.expected {
+			t.Errorf("%+v --> ожидалось: %s, получено: %s", test.config, test.expected, strings.Join(given, " "))
+		}
+
+		if ok := !strings.Contains(test.expected, "failed"); d.OK != ok {
+			t.Errorf("%+v --> ожидалось OK = %t", test.config, ok)
+		}
+	}
+
+	if d := dbmodel.Diagnose(context.Background(), tests[0].config); d.Server == nil || d.Server.Version == "" {
+		t.Errorf("ожидалась версия SQLite, получено: %+v", d.Server)
+	}
+}
@@ -0,0 +1,102 @@
+package dbmodel
+
+import (
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+// Dialect - всё, что отличает целевую базу данных: подключение, синтаксис SQL,
+// чтение метаданных и сеанс консоли. Новый драйвер добавляется реализацией Dialect и вызовом Register,
+// построитель запросов и сервисы при этом не меняются.
+type Dialect interface {
+	// Driver возвращает имя драйвера database/sql.
+	Driver() string
+	// DSN собирает строку подключения.
+	DSN(c Config) (string, error)
+	// Placeholder возвращает формат параметров запроса.
+	Placeholder() sq.PlaceholderFormat
+
+	// Ident заключает идентификатор в кавычки.
+	Ident(name string) string
+	// String встраивает строковую константу.
+	String(s string) string
+	// Order дополняет выражение сортировки направлением и порядком NULL (NullsFirst, NullsLast).
+	Order(expr string, desc bool, nulls string) string
+	// Limit добавляет LIMIT и OFFSET; нулевые значения не ограничивают выборку.
+	Limit(b sq.SelectBuilder, limit, offset uint64) sq.SelectBuilder
+	// Random возвращает выражение со случайным числом.
+	Random() string
+	// TableSample сообщает, поддерживается ли TABLESAMPLE.
+	TableSample() bool
+
+	// SchemaList читает схемы, доступные для выбора (nil, если схем нет).
+	SchemaList(ctx context.Context, b sq.StatementBuilderType) ([]string, error)
+	// TableList читает таблицы из схем schemaList (nil - схемы по умолчанию) в порядке списка;
+	// пустое имя - все таблицы. Типы колонок - как их называет база данных.
+	TableList(ctx context.Context, b sq.StatementBuilderType, schemaList []string, name string) ([]*Table, error)
+	// FunctionList читает функции из списка разрешённых.
+	FunctionList(ctx context.Context, b sq.StatementBuilderType, nameList []string) ([]*Function, error)
+	// NormalizeType приводит имя типа к имени PostgreSQL, в котором заданы неявные приведения
+	// и сигнатуры функций; "any" - произвольный тип.
+	NormalizeType(t string) string
+
+	// BackendID возвращает идентификатор серверного процесса соединения (0, если запрос нельзя отменить).
+	BackendID(ctx context.Context, conn *sql.Conn) (int64, error)
+	// CancelBackend отменяет текущий запрос серверного процесса.
+	CancelBackend(ctx context.Context, db Execer, pid int64) error
+	// BeginConsole начинает транзакцию для произвольного SQL.
+	BeginConsole(ctx context.Context, conn *sql.Conn, readOnly bool, timeout time.Duration) (*sql.Tx, error)
+	// ResetSession сбрасывает состояние сеанса перед возвратом соединения в пул.
+	ResetSession(ctx context.Context, conn *sql.Conn) error
+
+	// ConnError определяет по ошибке подключения, какой этап не пройден: StepAuth, StepDatabase
+	// или пустая строка, если причина неизвестна.
+	ConnError(err error) string
+	// ServerInfo читает версию сервера, текущего пользователя и его привилегии.
+	ServerInfo(ctx context.Context, db Querier) (ServerInfo, error)
+}
+
+type Execer interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}
+
+type Querier interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+var (
+	dialects   = map[string]Dialect{}
+	driverList []string //в порядке регистрации
+)
+
+// Register добавляет диалект под именем драйвера, которое указывается в Config.Driver.
+func Register(driver string, d Dialect) {
+	if _, ok := dialects[driver]; !ok {
+		driverList = append(driverList, driver)
+	}
+	dialects[driver] = d
+}
+
+func DialectOf(driver string) (Dialect, bool) {
+	d, ok := dialects[driver]
+	return d, ok
+}
+
+// DriverList возвращает поддерживаемые драйверы целевых баз данных.
+func DriverList() []string {
+	return append([]string(nil), driverList...)
+}
+
+func init() {
+	Register(PostgreSQL, postgres{})
+	Register(MySQL, mysqlDialect{})
+	Register(SQLite, sqlite{})
+}
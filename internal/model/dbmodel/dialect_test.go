@@ -0,0 +1,226 @@
+package dbmodel_test
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"datapoint/pkg/database"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeType(t *testing.T) {
+	tests := [...]struct {
+		driver, t, expected string
+	}{
+		{dbmodel.PostgreSQL, "INT4", "integer"},
+		{dbmodel.PostgreSQL, "character varying", "character varying"},
+		{dbmodel.PostgreSQL, `"any"`, "any"},
+		{dbmodel.MySQL, "varchar", "character varying"},
+		{dbmodel.MySQL, "UNSIGNED INT", "integer"},
+		{dbmodel.MySQL, "LONGBLOB", "bytea"},
+		{dbmodel.SQLite, "VARCHAR(100)", "text"},
+		{dbmodel.SQLite, "UNSIGNED BIG INT", "integer"},
+		{dbmodel.SQLite, "DECIMAL(10, 2)", "numeric"},
+		{dbmodel.SQLite, "", ""},
+	}
+
+	for _, test := range tests {
+		d, ok := dbmodel.DialectOf(test.driver)
+		if !ok {
+			t.Fatalf("диалект %s не зарегистрирован", test.driver)
+		}
+
+		if given := d.NormalizeType(test.t); given != test.expected {
+			t.Errorf("%s %s --> ожидалось: %s, получено: %s", test.driver, test.t, test.expected, given)
+		}
+	}
+}
+
+func TestDSN(t *testing.T) {
+	d, _ := dbmodel.DialectOf(dbmodel.PostgreSQL)
+
+	tests := [...]struct {
+		config   dbmodel.Config
+		expected string
+	}{
+		{
+			config:   dbmodel.Config{Host: "localhost", Port: 5432, User: "user", Password: `it's \ p@ss`, Name: "db"},
+			expected: `host='localhost' port='5432' user='user' password='it\'s \\ p@ss' dbname='db' sslmode='disable'`,
+		},
+		{
+			config:   dbmodel.Config{Host: "db.example.com", Port: 5432, User: "user", Name: "db", SSLMode: dbmodel.SSLVerifyFull},
+			expected: `host='db.example.com' port='5432' user='user' password='' dbname='db' sslmode='verify-full'`,
+		},
+	}
+
+	for _, test := range tests {
+		given, err := d.DSN(test.config)
+		if err != nil {
+			t.Errorf("%+v --> ошибка: %s", test.config, err)
+			continue
+		}
+
+		if given != test.expected {
+			t.Errorf("ожидалось: %s, получено: %s", test.expected, given)
+		}
+	}
+
+	if _, err := d.DSN(dbmodel.Config{SSLMode: dbmodel.SSLVerifyCA, SSLRootCert: "не сертификат"}); err == nil {
+		t.Errorf("ожидалась ошибка для некорректного сертификата")
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := [...]struct {
+		config   dbmodel.Config
+		expected string //пустая строка - ожидается ошибка
+	}{
+		{
+			config: dbmodel.Config{Driver: dbmodel.PostgreSQL, Host: "localhost", Port: 5432, User: "user", Name: "db",
+				Params: map[string]string{"search_path": "audit,public", "application_name": "datapoint", "connect_timeout": "5"}},
+			expected: `host='localhost' port='5432' user='user' password='' dbname='db' sslmode='disable' application_name='datapoint' connect_timeout='5' search_path='audit,public'`,
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.PostgreSQL, Host: "db1,db2", Port: 5432,
+				Params: map[string]string{"target_session_attrs": "read-write"}},
+			expected: `host='db1,db2' port='5432' user='' password='' dbname='' sslmode='disable' target_session_attrs='read-write'`,
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.PostgreSQL, Host: "localhost", Port: 5432, Params: map[string]string{"sslmode": "require"}},
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.MySQL, Host: "localhost", Port: 3306, User: "user", Name: "db",
+				Params: map[string]string{"timeout": "5s", "time_zone": "'+00:00'"}},
+			expected: "user@tcp(localhost:3306)/db?parseTime=true&timeout=5s&time_zone=%27%2B00%3A00%27",
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.MySQL, Host: "localhost", Port: 3306, Params: map[string]string{"timeout": "пять"}},
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.MySQL, Host: "localhost", Port: 3306, Params: map[string]string{"allowAllFiles": "true"}},
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.SQLite, Path: "/tmp/db.sqlite", Params: map[string]string{"_busy_timeout": "5000"}},
+			expected: "file:/tmp/db.sqlite?_busy_timeout=5000&mode=rw",
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.SQLite, Path: "/tmp/db.sqlite", Params: map[string]string{"mode": "rwc"}},
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.PostgreSQL, Host: "ignored", ConnString: "postgres://user@localhost/db?application_name=datapoint"},
+			expected: "postgres://user@localhost/db?application_name=datapoint",
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.PostgreSQL, ConnString: "postgres://localhost/db", Params: map[string]string{"application_name": "datapoint"}},
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.SQLite, ConnString: "file:/tmp/db.sqlite"},
+		},
+		{
+			config:   dbmodel.Config{Driver: dbmodel.MySQL, ConnString: "user@tcp(localhost:3306)/db?parseTime=true"},
+			expected: "user@tcp(localhost:3306)/db?parseTime=true",
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.MySQL, ConnString: "user@tcp(localhost:3306)/db?multiStatements=true"},
+		},
+		{
+			config: dbmodel.Config{Driver: dbmodel.MySQL, Host: "localhost", Port: 3306, Params: map[string]string{"multiStatements": "true"}},
+		},
+	}
+
+	for _, test := range tests {
+		_, given, err := test.config.Parse()
+		switch {
+		case test.expected == "" && err == nil:
+			t.Errorf("%+v --> ожидалась ошибка, получено: %s", test.config, given)
+		case test.expected != "" && err != nil:
+			t.Errorf("%+v --> ошибка: %s", test.config, err)
+		case given != test.expected && err == nil:
+			t.Errorf("ожидалось: %s, получено: %s", test.expected, given)
+		}
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "sqlite")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(dir, "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := filepath.Join(dir, "datapoint.db")
+
+	tests := [...]struct {
+		path        string
+		expectedErr bool
+	}{
+		{path: filepath.Join(dir, "example.db")},
+		{path: filepath.Join(dir, "nested", "example.db")},
+		{path: filepath.Join(dir, "..", "example.db"), expectedErr: true},
+		{path: filepath.Join(dir, "up", "example.db"), expectedErr: true},
+		{path: "/etc/passwd", expectedErr: true},
+		{path: dir, expectedErr: true},
+		{path: meta, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		c := dbmodel.Config{Driver: dbmodel.SQLite, Path: test.path}
+		if err := c.CheckPath(dir, "file:"+meta+"?_foreign_keys=on"); (err != nil) != test.expectedErr {
+			t.Errorf("%s --> ожидалась ошибка: %t, получено: %v", test.path, test.expectedErr, err)
+		}
+	}
+
+	c := dbmodel.Config{Driver: dbmodel.SQLite, Path: filepath.Join(dir, "example.db")}
+	if err := c.CheckPath(""); err == nil {
+		t.Errorf("ожидалась ошибка, если каталог файлов SQLite не задан")
+	}
+}
+
+func TestKeepSecrets(t *testing.T) {
+	old := dbmodel.Config{
+		SSLCert: "cert",
+		SSLKey:  "key",
+		SSH:     &database.SSHConfig{Host: "bastion", Password: "secret", PrivateKey: "pem", Passphrase: "phrase"},
+	}
+
+	c := dbmodel.Config{SSLCert: "cert", SSH: &database.SSHConfig{Host: "bastion"}}
+	c.KeepSecrets(old)
+	if c.SSLKey != "key" || *c.SSH != *old.SSH {
+		t.Errorf("ожидались прежние ключи и пароль, получено: %+v, %+v", c, c.SSH)
+	}
+
+	c = dbmodel.Config{SSLCert: "new cert", SSLKey: "new key", SSH: &database.SSHConfig{Host: "bastion", PrivateKey: "new pem"}}
+	c.KeepSecrets(old)
+	if c.SSLKey != "new key" || c.SSH.PrivateKey != "new pem" || c.SSH.Passphrase != "" || c.SSH.Password != "" {
+		t.Errorf("новые значения не должны заменяться прежними, а пароль при переходе на ключ - сохраняться, получено: %+v, %+v", c, c.SSH)
+	}
+
+	c = dbmodel.Config{SSH: &database.SSHConfig{Host: "bastion", Password: "new secret"}}
+	c.KeepSecrets(old)
+	if c.SSH.Password != "new secret" || c.SSH.PrivateKey != "" || c.SSH.Passphrase != "" {
+		t.Errorf("при переходе на пароль ключ не должен сохраняться, получено: %+v", c.SSH)
+	}
+
+	c = dbmodel.Config{}
+	c.KeepSecrets(old)
+	if c.SSLKey != "" || c.SSH != nil {
+		t.Errorf("ключ без сертификата и SSH без настроек не должны восстанавливаться, получено: %+v", c)
+	}
+
+	old = dbmodel.Config{Driver: dbmodel.PostgreSQL, ConnString: "host=db password=secret"}
+
+	c = dbmodel.Config{Driver: dbmodel.PostgreSQL}
+	c.KeepSecrets(old)
+	if c.ConnString != old.ConnString {
+		t.Errorf("ожидалась прежняя строка подключения, получено: %q", c.ConnString)
+	}
+
+	c = dbmodel.Config{Driver: dbmodel.PostgreSQL, Host: "db", Port: 5432}
+	c.KeepSecrets(old)
+	if c.ConnString != "" {
+		t.Errorf("при переходе на отдельные параметры строка подключения не должна восстанавливаться, получено: %q", c.ConnString)
+	}
+}
@@ -0,0 +1,123 @@
+package dbmodel
+
+import (
+	"context"
+	"strings"
+)
+
+var DefaultFunctionList = []string{
+	"avg", "count", "max", "min", "sum",
+	"string_agg", "array_agg", "stddev", "variance", "percentile_cont", "bool_and", "bool_or", "every",
+	"lower", "upper", "round", "date_part",
+}
+
+const (
+	Aggregate  = "aggregate"
+	OrderedSet = "ordered" //агрегат вида f(...) WITHIN GROUP (ORDER BY ...)
+	Scalar     = "scalar"
+)
+
+type Signature struct {
+	ArgTypeList []string
+	ReturnType  string
+}
+
+// implicit содержит неявные приведения типов, достаточные для проверки аргументов функций.
+var implicit = map[string][]string{
+	"smallint":                    {"integer", "bigint", "numeric", "real", "double precision"},
+	"integer":                     {"bigint", "numeric", "real", "double precision"},
+	"bigint":                      {"numeric", "real", "double precision"},
+	"numeric":                     {"real", "double precision"},
+	"real":                        {"double precision"},
+	"character varying":           {"text"},
+	"character":                   {"text"},
+	"date":                        {"timestamp without time zone", "timestamp with time zone"},
+	"timestamp without time zone": {"timestamp with time zone"},
+}
+
+// Accepts сообщает, можно ли передать значение типа t аргументом с индексом i.
+func (s Signature) Accepts(i int, t string) bool {
+	if i < 0 || i >= len(s.ArgTypeList) {
+		return false
+	}
+
+	argType := s.ArgTypeList[i]
+	if argType == t || strings.HasPrefix(argType, "any") {
+		return true
+	}
+
+	for _, c := range implicit[t] {
+		if c == argType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Function struct {
+	Name          string
+	Kind          string
+	TypeList      []string //nil == any
+	SignatureList []*Signature
+}
+
+func (db *DB) functionNameList() []string {
+	if list := db.GetInfo().FunctionList; list != nil {
+		return list
+	}
+	return DefaultFunctionList
+}
+
+// filterFunctionList отбирает из встроенных функций разрешённые для базы данных.
+func filterFunctionList(list []*Function, nameList []string) []*Function {
+	allowed := make(map[string]struct{}, len(nameList))
+	for _, name := range nameList {
+		allowed[strings.ToLower(name)] = struct{}{}
+	}
+
+	var result []*Function
+	for _, f := range list {
+		if _, ok := allowed[f.Name]; ok {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func (db *DB) FunctionList(ctx context.Context) ([]*Function, error) {
+	d, dialect, err := db.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	functionList, err := dialect.FunctionList(ctx, builder(d, dialect), db.functionNameList())
+	if err != nil {
+		return nil, err
+	}
+
+	//встроенные списки функций общие для всех баз данных, поэтому типы приводятся в копии
+	normalize := func(list []string) []string {
+		if list == nil {
+			return nil
+		}
+		result := make([]string, 0, len(list))
+		for _, t := range list {
+			result = append(result, dialect.NormalizeType(t))
+		}
+		return result
+	}
+
+	for i, f := range functionList {
+		n := &Function{Name: f.Name, Kind: f.Kind, TypeList: normalize(f.TypeList)}
+		for _, s := range f.SignatureList {
+			n.SignatureList = append(n.SignatureList, &Signature{
+				ArgTypeList: normalize(s.ArgTypeList),
+				ReturnType:  dialect.NormalizeType(s.ReturnType),
+			})
+		}
+		functionList[i] = n
+	}
+
+	return functionList, nil
+}
@@ -0,0 +1,56 @@
+package dbmodel
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	Unknown  = "unknown" //проверок ещё не было
+	Up       = "up"
+	Down     = "down"
+	Degraded = "degraded" //база данных отвечает, но медленнее допустимого
+)
+
+type Health struct {
+	Status    string
+	Error     string
+	Latency   time.Duration
+	CheckedAt time.Time
+}
+
+// Ping проверяет подключение, при необходимости подключаясь заново, и запоминает результат.
+// Ответ медленнее degraded считается деградацией; 0 - не проверять время ответа.
+func (db *DB) Ping(ctx context.Context, degraded time.Duration) Health {
+	start := time.Now()
+
+	d, _, err := db.conn(ctx)
+	if err == nil {
+		err = d.PingContext(ctx)
+	}
+
+	h := Health{Status: Up, Latency: time.Since(start), CheckedAt: time.Now().UTC()}
+	switch {
+	case err != nil:
+		h.Status, h.Error = Down, err.Error()
+	case degraded != 0 && h.Latency > degraded:
+		h.Status = Degraded
+	}
+
+	db.healthMu.Lock()
+	db.health = h
+	db.healthMu.Unlock()
+
+	return h
+}
+
+// Health возвращает результат последней проверки.
+func (db *DB) Health() Health {
+	db.healthMu.RLock()
+	defer db.healthMu.RUnlock()
+
+	if db.health.CheckedAt.IsZero() {
+		return Health{Status: Unknown}
+	}
+	return db.health
+}
@@ -0,0 +1,155 @@
+package dbmodel_test
+
+import (
+	"context"
+	"database/sql"
+	"datapoint/internal/model/dbmodel"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.db")
+
+	//файла ещё нет, поэтому подключение при запуске не удалось
+	db := &dbmodel.DB{Info: dbmodel.Info{Config: dbmodel.Config{Driver: dbmodel.SQLite, Path: path, ReadOnly: true}}}
+	defer db.Close()
+
+	if h := db.Health(); h.Status != dbmodel.Unknown {
+		t.Errorf("до проверки ожидался статус %s, получено: %s", dbmodel.Unknown, h.Status)
+	}
+
+	ctx := context.Background()
+
+	if h := db.Ping(ctx, 0); h.Status != dbmodel.Down || h.Error == "" {
+		t.Errorf("ожидался статус %s с ошибкой, получено: %+v", dbmodel.Down, h)
+	}
+
+	file, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	if h := db.Ping(ctx, 0); h.Status != dbmodel.Up || h.CheckedAt.IsZero() {
+		t.Errorf("после появления файла ожидался статус %s, получено: %+v", dbmodel.Up, h)
+	}
+
+	if h := db.Health(); h.Status != dbmodel.Up {
+		t.Errorf("ожидался сохранённый статус %s, получено: %s", dbmodel.Up, h.Status)
+	}
+}
+
+// TestSetInfo меняет настройки базы данных, к которой ещё не подключились, во время фоновой проверки;
+// гонки проверяются запуском с -race.
+func TestSetInfo(t *testing.T) {
+	dir := t.TempDir()
+
+	var paths [2]string
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("set_info_%d.db", i))
+		file, err := sql.Open("sqlite3", paths[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = file.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+			t.Fatal(err)
+		}
+		_ = file.Close()
+	}
+
+	db := &dbmodel.DB{Info: dbmodel.Info{Name: "0", Config: dbmodel.Config{Driver: dbmodel.SQLite, Path: paths[0]}}}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			db.Ping(ctx, 0)
+			_ = db.GetInfo()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			info := dbmodel.Info{Name: strconv.Itoa(i % 2), Config: dbmodel.Config{Driver: dbmodel.SQLite, Path: paths[i%2]}}
+			if err := db.SetInfo(info); err != nil {
+				t.Errorf("не удалось изменить настройки: %s", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if info := db.GetInfo(); info.Name != "1" || info.Config.Path != paths[1] {
+		t.Errorf("ожидались последние настройки, получено: %+v", info)
+	}
+
+	if h := db.Ping(ctx, 0); h.Status != dbmodel.Up {
+		t.Errorf("после смены настроек ожидался статус %s, получено: %+v", dbmodel.Up, h)
+	}
+}
+
+func TestPingTimeout(t *testing.T) {
+	//сервер принимает соединения, но не отвечает
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				for _, c := range conns {
+					_ = c.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	db := &dbmodel.DB{Info: dbmodel.Info{Name: "silent", Config: dbmodel.Config{
+		Driver: dbmodel.PostgreSQL, Host: "127.0.0.1", Port: port, User: "user", Name: "db",
+	}}}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	done := make(chan dbmodel.Health)
+	go func() { done <- db.Ping(ctx, 0) }()
+
+	//настройки доступны, пока идёт подключение
+	time.Sleep(50 * time.Millisecond)
+	info := make(chan dbmodel.Info)
+	go func() { info <- db.GetInfo() }()
+	select {
+	case <-info:
+	case <-time.After(time.Second):
+		t.Fatal("GetInfo ждёт подключения к недоступному серверу")
+	}
+
+	select {
+	case h := <-done:
+		if h.Status != dbmodel.Down {
+			t.Errorf("ожидался статус %s, получено: %+v", dbmodel.Down, h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("проверка не уложилась в ограничение времени")
+	}
+}
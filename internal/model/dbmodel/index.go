@@ -0,0 +1,63 @@
+package dbmodel
+
+type Index struct {
+	Name       string
+	ColumnList []string //колонки или выражения в порядке индекса
+	IsUnique   bool
+	Method     string //btree, hash, gin...
+	Predicate  string //условие частичного индекса
+}
+
+type Constraint struct {
+	Name       string
+	ColumnList []string //колонки ограничения UNIQUE
+	Expression string   //выражение ограничения CHECK
+}
+
+type tableKey struct {
+	schema, name string
+}
+
+// tableMap индексирует таблицы для заполнения индексов и ограничений, прочитанных отдельным запросом.
+func tableMap(tableList []*Table) map[tableKey]*Table {
+	tables := make(map[tableKey]*Table, len(tableList))
+	for _, t := range tableList {
+		tables[tableKey{t.Schema, t.Name}] = t
+	}
+	return tables
+}
+
+// Действия ON DELETE и ON UPDATE внешнего ключа.
+const (
+	NoAction   = "NO ACTION"
+	Restrict   = "RESTRICT"
+	Cascade    = "CASCADE"
+	SetNull    = "SET NULL"
+	SetDefault = "SET DEFAULT"
+)
+
+// ForeignKey - внешний ключ; колонки ColumnList ссылаются на RefColumnList попарно.
+type ForeignKey struct {
+	Name          string //пустое значение в SQLite
+	ColumnList    []string
+	RefSchema     string
+	RefTable      string
+	RefColumnList []string
+	OnDelete      string
+	OnUpdate      string
+}
+
+// linkFK заполняет Column.FK по внешним ключам из одной колонки.
+func (t *Table) linkFK() {
+	for _, fk := range t.FKList {
+		if len(fk.ColumnList) != 1 || len(fk.RefColumnList) != 1 {
+			continue
+		}
+
+		for _, c := range t.ColumnList {
+			if c.Name == fk.ColumnList[0] && c.FK == nil {
+				c.FK = &FK{Schema: fk.RefSchema, TableName: fk.RefTable, ColumnName: fk.RefColumnList[0]}
+			}
+		}
+	}
+}
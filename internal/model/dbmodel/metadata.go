@@ -0,0 +1,53 @@
+package dbmodel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Metadata - таблицы и функции базы данных, прочитанные вместе. После загрузки не изменяется,
+// поэтому может использоваться несколькими запросами одновременно.
+type Metadata struct {
+	TableList    []*Table
+	FunctionList []*Function
+	ETag         string //совпадает у одинаковых метаданных, даже загруженных в разное время
+	LoadedAt     time.Time
+}
+
+func (db *DB) Metadata(ctx context.Context) (*Metadata, error) {
+	tableList, err := db.TableList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить таблицы базы данных: %s", err)
+	}
+
+	var functionList []*Function
+	if functionList, err = db.FunctionList(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось получить функции базы данных: %s", err)
+	}
+
+	m := &Metadata{TableList: tableList, FunctionList: functionList, LoadedAt: time.Now().UTC()}
+
+	var data []byte
+	if data, err = json.Marshal([2]any{tableList, functionList}); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	m.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	return m, nil
+}
+
+// TableByName ищет таблицу по имени так же, как DB.TableByName, но без обращения к базе данных.
+func (m *Metadata) TableByName(name string) (*Table, error) {
+	for _, t := range m.TableList {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("таблицы с именем %s не существует", name)
+}
@@ -0,0 +1,589 @@
+package dbmodel
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"datapoint/pkg/database"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/go-sql-driver/mysql"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mysqlDialect - MySQL и MariaDB.
+type mysqlDialect struct{}
+
+func (mysqlDialect) Driver() string {
+	return database.Mysql
+}
+
+// DSN регистрирует настройки TLS в драйвере под именем, зависящим от содержимого:
+// строка подключения ссылается на них по имени.
+func (mysqlDialect) DSN(c Config) (string, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port)))
+	cfg.DBName = c.Name
+	cfg.ParseTime = true
+
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if tlsConfig != nil {
+		sum := sha256.Sum256([]byte(strings.Join([]string{c.Host, c.sslMode(), c.SSLRootCert, c.SSLCert, c.SSLKey}, "\x00")))
+		cfg.TLSConfig = "datapoint-" + hex.EncodeToString(sum[:8])
+		if err = mysql.RegisterTLSConfig(cfg.TLSConfig, tlsConfig); err != nil {
+			return "", err
+		}
+	}
+
+	if err = checkParams(MySQL, c.Params, mysqlParams); err != nil {
+		return "", err
+	}
+	if len(c.Params) == 0 {
+		return cfg.FormatDSN(), nil
+	}
+
+	//параметры разбирает сам драйвер, заодно проверяя значения (например, timeout=10s)
+	dsn := cfg.FormatDSN()
+	for _, name := range paramNames(c.Params) {
+		dsn += "&" + name + "=" + url.QueryEscape(c.Params[name])
+	}
+	if cfg, err = mysql.ParseDSN(dsn); err != nil {
+		return "", err
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// mysqlCheckConnString запрещает multiStatements: с ним консоль выполнила бы несколько операторов,
+// а проверка NewStatement рассчитана на один.
+func mysqlCheckConnString(dsn string) error {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+	if cfg.MultiStatements {
+		return errors.New("параметр multiStatements в строке подключения не поддерживается")
+	}
+	return nil
+}
+
+func (mysqlDialect) ConnError(err error) string {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return ""
+	}
+
+	switch myErr.Number {
+	case 1045: //ER_ACCESS_DENIED_ERROR
+		return StepAuth
+	case 1044, 1049: //ER_DBACCESS_DENIED_ERROR, ER_BAD_DB_ERROR
+		return StepDatabase
+	}
+	return ""
+}
+
+// ServerInfo возвращает привилегии строками SHOW GRANTS.
+func (mysqlDialect) ServerInfo(ctx context.Context, db Querier) (ServerInfo, error) {
+	var info ServerInfo
+	if err := db.QueryRowContext(ctx, "SELECT VERSION(), CURRENT_USER()").Scan(&info.Version, &info.User); err != nil {
+		return ServerInfo{}, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SHOW GRANTS")
+	if err != nil {
+		return ServerInfo{}, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var grant string
+		if err = rows.Scan(&grant); err != nil {
+			return ServerInfo{}, err
+		}
+		info.Privileges = append(info.Privileges, grant)
+	}
+
+	return info, rows.Err()
+}
+
+// mysqlParams - дополнительные параметры подключения: настройки драйвера и системные переменные сеанса.
+var mysqlParams = map[string]bool{
+	"timeout":               true,
+	"readTimeout":           true,
+	"writeTimeout":          true,
+	"charset":               true,
+	"collation":             true,
+	"loc":                   true,
+	"maxAllowedPacket":      true,
+	"time_zone":             true,
+	"sql_mode":              true,
+	"transaction_isolation": true,
+	"max_execution_time":    true,
+}
+
+func (mysqlDialect) Placeholder() sq.PlaceholderFormat {
+	return sq.Question
+}
+
+func (mysqlDialect) Ident(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// String экранирует и обратную косую черту, которая в MySQL по умолчанию начинает escape-последовательность.
+func (mysqlDialect) String(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+}
+
+// Order эмулирует NULLS FIRST/LAST дополнительной сортировкой по IS NULL:
+// в MySQL NULL меньше любого значения.
+func (mysqlDialect) Order(expr string, desc bool, nulls string) string {
+	order := expr
+	if desc {
+		order += " DESC"
+	}
+
+	switch {
+	case nulls == NullsFirst && desc:
+		return fmt.Sprintf("%s IS NULL DESC, %s", expr, order)
+	case nulls == NullsLast && !desc:
+		return fmt.Sprintf("%s IS NULL, %s", expr, order)
+	}
+
+	return order
+}
+
+// Limit подставляет максимальный LIMIT, если задан только OFFSET: MySQL не допускает OFFSET без LIMIT.
+func (mysqlDialect) Limit(b sq.SelectBuilder, limit, offset uint64) sq.SelectBuilder {
+	if offset != 0 && limit == 0 {
+		limit = math.MaxUint64
+	}
+	return postgres{}.Limit(b, limit, offset)
+}
+
+func (mysqlDialect) Random() string {
+	return "RAND()"
+}
+
+func (mysqlDialect) TableSample() bool {
+	return false
+}
+
+func (mysqlDialect) SchemaList(context.Context, sq.StatementBuilderType) ([]string, error) {
+	return nil, nil
+}
+
+// TableList читает таблицы текущей базы данных; схемы в MySQL - это базы данных, поэтому schemaList не учитывается.
+// Имена ограничений (например, PRIMARY) уникальны только в пределах таблицы, поэтому соединение идёт по схеме,
+// таблице и имени, а внешний ключ берётся из key_column_usage.
+func (mysqlDialect) TableList(ctx context.Context, b sq.StatementBuilderType, _ []string, name string) ([]*Table, error) {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"c.table_name": name}
+	}
+
+	rows, err := b.Select(
+		"''",
+		"c.table_name",
+		"CASE WHEN t.table_type = 'VIEW' THEN 'view' ELSE 'table' END",
+		"v.view_definition",
+		"CASE WHEN t.table_type = 'VIEW' THEN NULL ELSE NULLIF(t.table_comment, '') END", //у представлений MySQL пишет сюда VIEW
+		"c.column_name",
+		"c.data_type",
+		"c.is_nullable = 'NO' AND c.column_default IS NULL AND c.extra NOT LIKE '%auto_increment%'",
+		"c.column_key = 'PRI'",
+		"c.column_default",
+		"c.extra LIKE '%auto_increment%'",
+		"false",
+		"c.extra LIKE '%GENERATED%'",
+		"NULLIF(c.generation_expression, '')",
+		"c.character_maximum_length",
+		"CASE WHEN c.data_type = 'decimal' THEN c.numeric_precision END",
+		"CASE WHEN c.data_type = 'decimal' THEN c.numeric_scale END",
+		"NULL",
+		"CASE WHEN c.data_type IN ('enum', 'set') THEN c.column_type END",
+		"NULLIF(c.column_comment, '')",
+	).From("information_schema.columns c").
+		Join("information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name").
+		LeftJoin("information_schema.views v ON v.table_schema = c.table_schema AND v.table_name = c.table_name").
+		Where("c.table_schema = DATABASE()").
+		Where(where).
+		OrderBy("c.table_name", "c.ordinal_position").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tableList, err := scanTableList(rows, mysqlEnumList)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = mysqlIndexList(ctx, b, name, tableList); err != nil {
+		return nil, err
+	}
+
+	if err = mysqlCheckList(ctx, b, name, tableList); err != nil {
+		return nil, err
+	}
+
+	if err = mysqlForeignKeyList(ctx, b, name, tableList); err != nil {
+		return nil, err
+	}
+
+	return tableList, nil
+}
+
+// mysqlIndexList читает индексы из information_schema.statistics. Ограничение UNIQUE в MySQL -
+// это уникальный индекс, поэтому оно попадает и в список ограничений.
+func mysqlIndexList(ctx context.Context, b sq.StatementBuilderType, name string, tableList []*Table) error {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"s.table_name": name}
+	}
+
+	rows, err := b.Select(
+		"s.table_name",
+		"s.index_name",
+		"s.non_unique = 0",
+		"lower(s.index_type)",
+		"COALESCE(s.column_name, '')", //NULL для функциональной части индекса
+		"tc.constraint_type",
+	).From("information_schema.statistics s").
+		LeftJoin("information_schema.table_constraints tc ON tc.table_schema = s.table_schema "+
+			"AND tc.table_name = s.table_name AND tc.constraint_name = s.index_name").
+		Where("s.table_schema = DATABASE()").
+		Where(where).
+		OrderBy("s.table_name", "s.index_name", "s.seq_in_index").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var (
+		tables = tableMap(tableList)
+		unique = make(map[*Index]bool) //индексы ограничений UNIQUE
+		lastI  *Index
+		lastT  *Table
+	)
+
+	for rows.Next() {
+		var (
+			key        tableKey
+			i          = new(Index)
+			column     string
+			constraint *string
+		)
+
+		if err = rows.Scan(&key.name, &i.Name, &i.IsUnique, &i.Method, &column, &constraint); err != nil {
+			return err
+		}
+
+		t, ok := tables[key]
+		if !ok {
+			continue
+		}
+
+		if lastI == nil || lastT != t || lastI.Name != i.Name {
+			lastI, lastT = i, t
+			t.IndexList = append(t.IndexList, i)
+			if constraint != nil && *constraint == "UNIQUE" {
+				unique[i] = true
+			}
+		}
+
+		lastI.ColumnList = append(lastI.ColumnList, column)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range tableList {
+		for _, i := range t.IndexList {
+			if unique[i] {
+				t.UniqueList = append(t.UniqueList, &Constraint{Name: i.Name, ColumnList: i.ColumnList})
+			}
+		}
+	}
+
+	return nil
+}
+
+// mysqlEnumList разбирает значения из типа колонки вида enum('a','b') или set(...); апостроф в значении удвоен.
+func mysqlEnumList(columnType string) ([]string, error) {
+	start, end := strings.IndexByte(columnType, '('), strings.LastIndexByte(columnType, ')')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("неизвестный формат перечисления %s", columnType)
+	}
+
+	var (
+		list  []string
+		value strings.Builder
+		s     = columnType[start+1 : end]
+	)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\'' {
+			return nil, fmt.Errorf("неизвестный формат перечисления %s", columnType)
+		}
+
+		value.Reset()
+		for i++; ; i++ {
+			if i >= len(s) {
+				return nil, fmt.Errorf("неизвестный формат перечисления %s", columnType)
+			}
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i++
+				} else {
+					break
+				}
+			}
+			value.WriteByte(s[i])
+		}
+		list = append(list, value.String())
+
+		//после значения идёт запятая или конец списка
+		if i+1 < len(s) {
+			if s[i+1] != ',' {
+				return nil, fmt.Errorf("неизвестный формат перечисления %s", columnType)
+			}
+			i++
+		}
+	}
+
+	return list, nil
+}
+
+// mysqlCheckList читает ограничения CHECK: они есть в MySQL 8.0.16 и MariaDB 10.2,
+// в более ранних версиях таблицы check_constraints нет и список остаётся пустым.
+func mysqlCheckList(ctx context.Context, b sq.StatementBuilderType, name string, tableList []*Table) error {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"tc.table_name": name}
+	}
+
+	rows, err := b.Select("tc.table_name", "cc.constraint_name", "cc.check_clause").
+		From("information_schema.check_constraints cc").
+		Join("information_schema.table_constraints tc ON tc.constraint_schema = cc.constraint_schema "+
+			"AND tc.constraint_name = cc.constraint_name AND tc.constraint_type = 'CHECK'").
+		Where("cc.constraint_schema = DATABASE()").
+		Where(where).
+		OrderBy("tc.table_name", "cc.constraint_name").
+		QueryContext(ctx)
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == 1109 { //ER_UNKNOWN_TABLE
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tables := tableMap(tableList)
+	for rows.Next() {
+		var (
+			key tableKey
+			c   = new(Constraint)
+		)
+
+		if err = rows.Scan(&key.name, &c.Name, &c.Expression); err != nil {
+			return err
+		}
+
+		if t, ok := tables[key]; ok {
+			t.CheckList = append(t.CheckList, c)
+		}
+	}
+
+	return rows.Err()
+}
+
+// mysqlForeignKeyList читает внешние ключи: имя внешнего ключа в MySQL уникально в пределах базы данных.
+func mysqlForeignKeyList(ctx context.Context, b sq.StatementBuilderType, name string, tableList []*Table) error {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"rc.table_name": name}
+	}
+
+	rows, err := b.Select(
+		"rc.table_name",
+		"rc.constraint_name",
+		"rc.referenced_table_name",
+		"rc.delete_rule",
+		"rc.update_rule",
+		"kcu.column_name",
+		"kcu.referenced_column_name",
+	).From("information_schema.referential_constraints rc").
+		Join("information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema "+
+			"AND kcu.constraint_name = rc.constraint_name AND kcu.table_name = rc.table_name").
+		Where("rc.constraint_schema = DATABASE()").
+		Where(where).
+		OrderBy("rc.table_name", "rc.constraint_name", "kcu.ordinal_position").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var (
+		tables = tableMap(tableList)
+		lastFK *ForeignKey
+		lastT  *Table
+	)
+
+	for rows.Next() {
+		var (
+			key               tableKey
+			fk                = new(ForeignKey)
+			column, refColumn string
+		)
+
+		if err = rows.Scan(&key.name, &fk.Name, &fk.RefTable, &fk.OnDelete, &fk.OnUpdate, &column, &refColumn); err != nil {
+			return err
+		}
+
+		t, ok := tables[key]
+		if !ok {
+			continue
+		}
+
+		if lastFK == nil || lastT != t || lastFK.Name != fk.Name {
+			lastFK, lastT = fk, t
+			t.FKList = append(t.FKList, fk)
+		}
+
+		lastFK.ColumnList = append(lastFK.ColumnList, column)
+		lastFK.RefColumnList = append(lastFK.RefColumnList, refColumn)
+	}
+
+	return rows.Err()
+}
+
+// FunctionList отбирает встроенные функции: их нет в information_schema.routines.
+func (mysqlDialect) FunctionList(_ context.Context, _ sq.StatementBuilderType, nameList []string) ([]*Function, error) {
+	return filterFunctionList(mysqlFunctionList, nameList), nil
+}
+
+// mysqlTypes - типы MySQL из information_schema и go-sql-driver/mysql с именами PostgreSQL.
+var mysqlTypes = map[string]string{
+	"tinyint":    "smallint",
+	"year":       "smallint",
+	"mediumint":  "integer",
+	"int":        "integer",
+	"decimal":    "numeric",
+	"float":      "real",
+	"double":     "double precision",
+	"char":       "character",
+	"varchar":    "character varying",
+	"enum":       "character varying",
+	"set":        "character varying",
+	"tinytext":   "text",
+	"mediumtext": "text",
+	"longtext":   "text",
+	"datetime":   "timestamp without time zone",
+	"timestamp":  "timestamp without time zone",
+	"time":       "time without time zone",
+	"binary":     "bytea",
+	"varbinary":  "bytea",
+	"tinyblob":   "bytea",
+	"blob":       "bytea",
+	"mediumblob": "bytea",
+	"longblob":   "bytea",
+}
+
+func (mysqlDialect) NormalizeType(t string) string {
+	t = strings.TrimPrefix(strings.ToLower(t), "unsigned ")
+	if n, ok := mysqlTypes[t]; ok {
+		return n
+	}
+	return t
+}
+
+func (mysqlDialect) BackendID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pid int64
+	err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&pid)
+	return pid, err
+}
+
+func (mysqlDialect) CancelBackend(ctx context.Context, db Execer, pid int64) error {
+	//KILL не принимает параметры
+	_, err := db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", pid))
+	return err
+}
+
+// BeginConsole ограничивает время выполнения SELECT на стороне сервера: max_execution_time в MySQL,
+// max_statement_time (в секундах) в MariaDB.
+func (mysqlDialect) BeginConsole(ctx context.Context, conn *sql.Conn, readOnly bool, timeout time.Duration) (*sql.Tx, error) {
+	if timeout > 0 {
+		//SET не принимает параметры
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds()))
+
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && myErr.Number == 1193 { //ER_UNKNOWN_SYSTEM_VARIABLE
+			_, err = conn.ExecContext(ctx, fmt.Sprintf("SET SESSION max_statement_time = %g", timeout.Seconds()))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+}
+
+// ResetSession закрывает соединение вместо возврата в пул: драйвер не даёт сбросить сеанс
+// (COM_RESET_CONNECTION), а переменные, временные таблицы и настройки сеанса не сбрасываются одним оператором.
+func (mysqlDialect) ResetSession(_ context.Context, conn *sql.Conn) error {
+	err := conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+	return err
+}
+
+// mysqlFunctionList - встроенные функции MySQL с сигнатурами, заданными вручную;
+// типы аргументов, кроме строковых и дат, не проверяются.
+var mysqlFunctionList = []*Function{
+	{Name: "avg", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "numeric"}}},
+	{Name: "count", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "bigint"}}},
+	{Name: "max", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "any"}}},
+	{Name: "min", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "any"}}},
+	{Name: "sum", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "numeric"}}},
+	{Name: "stddev", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "double precision"}}},
+	{Name: "variance", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "double precision"}}},
+	{Name: "group_concat", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "text"}}},
+	{Name: "bit_and", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "bigint"}}},
+	{Name: "bit_or", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "bigint"}}},
+	{Name: "lower", Kind: Scalar, TypeList: mysqlStringTypes, SignatureList: []*Signature{{ArgTypeList: []string{"text"}, ReturnType: "text"}}},
+	{Name: "upper", Kind: Scalar, TypeList: mysqlStringTypes, SignatureList: []*Signature{{ArgTypeList: []string{"text"}, ReturnType: "text"}}},
+	{Name: "round", Kind: Scalar, SignatureList: []*Signature{
+		{ArgTypeList: []string{"any"}, ReturnType: "numeric"},
+		{ArgTypeList: []string{"any", "integer"}, ReturnType: "numeric"},
+	}},
+	{Name: "year", Kind: Scalar, TypeList: mysqlDateTypes, SignatureList: []*Signature{
+		{ArgTypeList: []string{"timestamp with time zone"}, ReturnType: "integer"},
+	}},
+}
+
+var (
+	mysqlStringTypes = []string{"character", "character varying", "text"}
+	mysqlDateTypes   = []string{"date", "timestamp without time zone"}
+)
@@ -0,0 +1,172 @@
+//go:build integration
+
+package dbmodel_test
+
+import (
+	"context"
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/querymodel"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMySQL выполняется на локальном контейнере:
+//
+//	docker run --rm -p 3306:3306 -e MYSQL_ROOT_PASSWORD=secret -e MYSQL_DATABASE=datapoint mysql:8
+//	go test -tags integration ./internal/model/dbmodel/
+//
+// Параметры подключения переопределяются переменными MYSQL_HOST, MYSQL_PORT, MYSQL_USER, MYSQL_PASSWORD, MYSQL_DB.
+func TestMySQL(t *testing.T) {
+	port, err := strconv.Atoi(env("MYSQL_PORT", "3306"))
+	if err != nil {
+		t.Fatalf("некорректный порт: %s", err)
+	}
+
+	db, err := dbmodel.New(dbmodel.Info{
+		Name: "mysql",
+		Config: dbmodel.Config{
+			Host:     env("MYSQL_HOST", "127.0.0.1"),
+			Port:     uint16(port),
+			User:     env("MYSQL_USER", "root"),
+			Password: env("MYSQL_PASSWORD", "secret"),
+			Name:     env("MYSQL_DB", "datapoint"),
+			Driver:   dbmodel.MySQL,
+		},
+	})
+	if err != nil {
+		t.Fatalf("не удалось подключиться к MySQL: %s", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	for _, query := range [...]string{
+		"DROP TABLE IF EXISTS orders",
+		"DROP TABLE IF EXISTS customer",
+		"CREATE TABLE customer (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(100) NOT NULL, note TEXT, " +
+			"status ENUM('new', 'it''s done') NOT NULL DEFAULT 'new' COMMENT 'состояние') COMMENT 'покупатели'",
+		"CREATE TABLE orders (id INT AUTO_INCREMENT PRIMARY KEY, customer_id INT NOT NULL, total DECIMAL(10, 2), " +
+			"FOREIGN KEY (customer_id) REFERENCES customer (id))",
+		`INSERT INTO customer (name, note) VALUES ('Анна', 'C:\\temp'), ('Борис', NULL)`,
+		"INSERT INTO orders (customer_id, total) VALUES (1, 10.50), (1, 4.50), (2, 7.00)",
+	} {
+		if _, err = db.ExecContext(ctx, query); err != nil {
+			t.Fatalf("%s --> %s", query, err)
+		}
+	}
+
+	tableList, err := db.TableList(ctx)
+	if err != nil {
+		t.Fatalf("не удалось получить список таблиц: %s", err)
+	}
+
+	columns := make(map[string]*dbmodel.Column)
+	for _, table := range tableList {
+		for _, c := range table.ColumnList {
+			columns[table.Name+"."+c.Name] = c
+		}
+	}
+
+	if c := columns["customer.id"]; c == nil || !c.IsPK || c.IsRequired {
+		t.Errorf("customer.id --> ожидался необязательный первичный ключ, получено: %+v", c)
+	}
+
+	if c := columns["customer.name"]; c == nil || c.Type != "character varying" || !c.IsRequired {
+		t.Errorf("customer.name --> ожидалась обязательная колонка character varying, получено: %+v", c)
+	}
+
+	if c := columns["customer.id"]; c == nil || !c.IsIdentity {
+		t.Errorf("customer.id --> ожидалась колонка AUTO_INCREMENT, получено: %+v", c)
+	}
+
+	if c := columns["customer.name"]; c == nil || c.MaxLength == nil || *c.MaxLength != 100 {
+		t.Errorf("customer.name --> ожидалась длина 100, получено: %+v", c)
+	}
+
+	if c := columns["customer.status"]; c == nil || strings.Join(c.EnumList, "|") != "new|it's done" ||
+		c.Default != "new" || c.Comment != "состояние" || c.IsRequired {
+		t.Errorf("customer.status --> ожидалось перечисление со значением по умолчанию и комментарием, получено: %+v", c)
+	}
+
+	if c := columns["orders.total"]; c == nil || c.Precision == nil || *c.Precision != 10 || c.Scale == nil || *c.Scale != 2 {
+		t.Errorf("orders.total --> ожидались точность 10 и масштаб 2, получено: %+v", c)
+	}
+
+	if c := columns["orders.customer_id"]; c == nil || c.FK == nil || c.FK.TableName != "customer" || c.FK.ColumnName != "id" {
+		t.Errorf("orders.customer_id --> ожидался внешний ключ на customer.id, получено: %+v", c)
+	}
+
+	functionList, err := db.FunctionList(ctx)
+	if err != nil {
+		t.Fatalf("не удалось получить список функций: %s", err)
+	}
+
+	info := querymodel.Info{
+		Type:  querymodel.Select,
+		Table: &querymodel.Table{TableKey: querymodel.TableKey{Name: "customer"}},
+		Columns: []*querymodel.Column{
+			{TableKey: querymodel.TableKey{Name: "customer"}, Column: dbmodel.Column{Name: "note"}},
+			{TableKey: querymodel.TableKey{Name: "customer"}, Column: dbmodel.Column{Name: "id"}, Function: "count"},
+		},
+		Where: []*querymodel.Column{
+			{TableKey: querymodel.TableKey{Name: "customer"}, Column: dbmodel.Column{Name: "note"}, Value: `C:\temp`},
+		},
+		OrderBy: []*querymodel.Column{
+			{TableKey: querymodel.TableKey{Name: "customer"}, Column: dbmodel.Column{Name: "note"}, Nulls: querymodel.NullsLast},
+		},
+	}
+
+	if err = info.Resolve(tableList, functionList); err != nil {
+		t.Fatalf("запрос не прошёл проверку: %s", err)
+	}
+
+	result, err := querymodel.New(info, db.B(), db.Dialect()).Execute(ctx, db)
+	if err != nil {
+		t.Fatalf("не удалось выполнить запрос: %s", err)
+	}
+
+	if result.RowCount != 1 || result.Data[0]["customer.note"] == nil || *result.Data[0]["customer.note"].(*any) != `C:\temp` {
+		t.Errorf("ожидалась одна строка с C:\\temp, получено: %+v", result.Data)
+	}
+
+	conn, _, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("не удалось получить соединение: %s", err)
+	}
+
+	tx, err := db.BeginConsole(ctx, conn, time.Second)
+	if err != nil {
+		t.Fatalf("не удалось начать транзакцию консоли: %s", err)
+	}
+
+	var timeout int64
+	if err = tx.QueryRowContext(ctx, "SELECT @@SESSION.max_execution_time, @x := 1").Scan(&timeout, new(int64)); err != nil || timeout != 1000 {
+		t.Errorf("ожидалось ограничение 1000 мс, получено: %d, %v", timeout, err)
+	}
+	_ = tx.Rollback()
+
+	if err = db.ResetSession(ctx, conn); err != nil {
+		t.Errorf("не удалось сбросить состояние сеанса: %s", err)
+	}
+	_ = conn.Close()
+
+	if conn, _, err = db.Conn(ctx); err != nil {
+		t.Fatalf("не удалось получить соединение: %s", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var x *int64
+	if err = conn.QueryRowContext(ctx, "SELECT @x").Scan(&x); err != nil || x != nil {
+		t.Errorf("ожидалось, что переменная сеанса не попала в пул, получено: %v, %v", x, err)
+	}
+}
+
+func env(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,28 @@
+package dbmodel
+
+import (
+	"fmt"
+	"sort"
+)
+
+// paramNames возвращает имена дополнительных параметров подключения по порядку,
+// чтобы строка подключения и ошибки не зависели от порядка обхода map.
+func paramNames(params map[string]string) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkParams отклоняет параметры, которых нет в allowed.
+// Параметры, задаваемые отдельными полями Config (host, user, sslmode...), в allowed не входят.
+func checkParams(driver string, params map[string]string, allowed map[string]bool) error {
+	for _, name := range paramNames(params) {
+		if !allowed[name] {
+			return fmt.Errorf("недопустимый параметр подключения %s для драйвера %s", name, driver)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,549 @@
+package dbmodel
+
+import (
+	"context"
+	"database/sql"
+	"datapoint/pkg/database"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type postgres struct{}
+
+func (postgres) Driver() string {
+	return database.Postgres
+}
+
+// DSN собирает строку подключения в формате ключ=значение: в URL lib/pq не сохраняет переводы строк,
+// а сертификаты передаются содержимым (sslinline).
+func (postgres) DSN(c Config) (string, error) {
+	//сертификаты проверяются заранее, чтобы ошибка не откладывалась до подключения
+	if _, err := c.TLSConfig(); err != nil {
+		return "", err
+	}
+
+	//несколько серверов через запятую и target_session_attrs разбирает database, а не lib/pq
+	if err := checkParams(PostgreSQL, c.Params, pgParams); err != nil {
+		return "", err
+	}
+
+	options := [][2]string{
+		{"host", c.Host},
+		{"port", strconv.Itoa(int(c.Port))},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Name},
+		{"sslmode", c.sslMode()},
+	}
+
+	if c.sslMode() != SSLDisable && (len(c.SSLRootCert) != 0 || len(c.SSLCert) != 0) {
+		options = append(options, [2]string{"sslinline", "true"})
+		for _, o := range [...][2]string{{"sslrootcert", c.SSLRootCert}, {"sslcert", c.SSLCert}, {"sslkey", c.SSLKey}} {
+			if len(o[1]) != 0 {
+				options = append(options, o)
+			}
+		}
+	}
+
+	for _, name := range paramNames(c.Params) {
+		options = append(options, [2]string{name, c.Params[name]})
+	}
+
+	dsn := make([]string, 0, len(options))
+	for _, o := range options {
+		dsn = append(dsn, o[0]+"='"+strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(o[1])+"'")
+	}
+	return strings.Join(dsn, " "), nil
+}
+
+// pgParams - дополнительные параметры подключения. Параметры, которые lib/pq не разбирает сам
+// (application_name, search_path и другие настройки сервера), передаются серверу при подключении.
+var pgParams = map[string]bool{
+	"application_name":                    true,
+	"fallback_application_name":           true,
+	"connect_timeout":                     true,
+	"target_session_attrs":                true,
+	"options":                             true,
+	"search_path":                         true,
+	"statement_timeout":                   true,
+	"lock_timeout":                        true,
+	"idle_in_transaction_session_timeout": true,
+	"timezone":                            true,
+	"datestyle":                           true,
+	"client_encoding":                     true,
+}
+
+func (postgres) Placeholder() sq.PlaceholderFormat {
+	return sq.Dollar
+}
+
+func (postgres) Ident(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgres) String(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (postgres) Order(expr string, desc bool, nulls string) string {
+	if desc {
+		expr += " DESC"
+	}
+
+	switch nulls {
+	case NullsFirst:
+		expr += " NULLS FIRST"
+	case NullsLast:
+		expr += " NULLS LAST"
+	}
+
+	return expr
+}
+
+func (postgres) Limit(b sq.SelectBuilder, limit, offset uint64) sq.SelectBuilder {
+	if limit != 0 {
+		b = b.Limit(limit)
+	}
+
+	if offset != 0 {
+		b = b.Offset(offset)
+	}
+
+	return b
+}
+
+func (postgres) Random() string {
+	return "random()"
+}
+
+func (postgres) TableSample() bool {
+	return true
+}
+
+// DefaultSchemaList - схемы, из которых читаются таблицы, если для базы данных они не выбраны.
+var DefaultSchemaList = []string{"public"}
+
+func (postgres) SchemaList(ctx context.Context, b sq.StatementBuilderType) ([]string, error) {
+	rows, err := b.Select("schema_name").
+		From("information_schema.schemata").
+		Where("schema_name <> 'information_schema' AND schema_name NOT LIKE 'pg\\_%'").
+		OrderBy("schema_name").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var list []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		list = append(list, name)
+	}
+
+	return list, rows.Err()
+}
+
+// TableList читает таблицы, индексы и ограничения из pg_catalog: information_schema соединяет ограничения
+// по имени, которое уникально только в пределах таблицы, а внешний ключ может ссылаться на таблицу другой схемы.
+func (postgres) TableList(ctx context.Context, b sq.StatementBuilderType, schemaList []string, name string) ([]*Table, error) {
+	if schemaList == nil {
+		schemaList = DefaultSchemaList
+	}
+
+	where := sq.And{sq.Eq{"n.nspname": schemaList}}
+	if name != "" {
+		where = append(where, sq.Eq{"cl.relname": name})
+	}
+
+	//information_schema.columns не содержит материализованных представлений, поэтому колонки читаются из pg_catalog
+	rows, err := b.Select(
+		"n.nspname",
+		"cl.relname",
+		"CASE cl.relkind WHEN 'v' THEN 'view' WHEN 'm' THEN 'materialized view' WHEN 'f' THEN 'foreign table' "+
+			"WHEN 'p' THEN 'partitioned table' ELSE 'table' END",
+		"CASE WHEN cl.relkind IN ('v', 'm') THEN pg_get_viewdef(cl.oid) END",
+		"obj_description(cl.oid, 'pg_class')",
+		"a.attname",
+		"format_type(a.atttypid, NULL)",
+		"a.attnotnull AND NOT a.atthasdef AND a.attidentity = ''",
+		"pk.oid IS NOT NULL",
+		//выражение вычисляемой колонки хранится там же, где значение по умолчанию
+		"CASE WHEN a.attgenerated = '' THEN pg_get_expr(d.adbin, d.adrelid) END",
+		"a.attidentity <> ''",
+		"COALESCE(a.attgenerated = '' AND pg_get_expr(d.adbin, d.adrelid) LIKE 'nextval(%', false)",
+		"a.attgenerated <> ''",
+		"CASE WHEN a.attgenerated <> '' THEN pg_get_expr(d.adbin, d.adrelid) END",
+		//typmod: длина для char и varchar, точность и масштаб для numeric, смещённые на 4 байта заголовка
+		"CASE WHEN a.atttypid IN ('bpchar'::regtype, 'varchar'::regtype) AND a.atttypmod > 0 THEN a.atttypmod - 4 END",
+		"CASE WHEN a.atttypid = 'numeric'::regtype AND a.atttypmod > 0 THEN ((a.atttypmod - 4) >> 16) & 65535 END",
+		"CASE WHEN a.atttypid = 'numeric'::regtype AND a.atttypmod > 0 THEN (a.atttypmod - 4) & 65535 END",
+		"CASE WHEN t.typcategory = 'A' THEN format_type(t.typelem, NULL) END",
+		"(SELECT json_agg(e.enumlabel ORDER BY e.enumsortorder) FROM pg_catalog.pg_enum e "+
+			"WHERE e.enumtypid = CASE WHEN t.typcategory = 'A' THEN t.typelem ELSE t.oid END)::text",
+		"col_description(cl.oid, a.attnum)",
+	).From("pg_catalog.pg_class cl").
+		Join("pg_catalog.pg_namespace n ON n.oid = cl.relnamespace").
+		Join("pg_catalog.pg_attribute a ON a.attrelid = cl.oid AND a.attnum > 0 AND NOT a.attisdropped").
+		Join("pg_catalog.pg_type t ON t.oid = a.atttypid").
+		LeftJoin("pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum").
+		LeftJoin("pg_catalog.pg_constraint pk ON pk.conrelid = cl.oid AND pk.contype = 'p' AND a.attnum = ANY(pk.conkey)").
+		Where("cl.relkind IN ('r', 'v', 'm', 'f', 'p')").
+		//как information_schema: только доступные пользователю таблицы
+		Where("has_any_column_privilege(cl.oid, 'SELECT')").
+		Where(where).
+		OrderBy("n.nspname", "cl.relname", "a.attnum").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tableList, err := scanTableList(rows, jsonList)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = pgIndexList(ctx, b, where, tableList); err != nil {
+		return nil, err
+	}
+
+	if err = pgConstraintList(ctx, b, where, tableList); err != nil {
+		return nil, err
+	}
+
+	if err = pgForeignKeyList(ctx, b, where, tableList); err != nil {
+		return nil, err
+	}
+
+	//таблица без схемы ищется в первой схеме списка, поэтому порядок схем сохраняется
+	position := make(map[string]int, len(schemaList))
+	for i, schema := range schemaList {
+		position[schema] = i
+	}
+	sort.SliceStable(tableList, func(i, j int) bool {
+		return position[tableList[i].Schema] < position[tableList[j].Schema]
+	})
+
+	return tableList, nil
+}
+
+// pgIndexList читает индексы таблиц; where - условие на n.nspname и cl.relname таблицы.
+func pgIndexList(ctx context.Context, b sq.StatementBuilderType, where sq.Sqlizer, tableList []*Table) error {
+	rows, err := b.Select(
+		"n.nspname",
+		"cl.relname",
+		"i.relname",
+		"ix.indisunique",
+		"am.amname",
+		"COALESCE(pg_get_expr(ix.indpred, ix.indrelid, true), '')",
+		//ключевые колонки без INCLUDE; для индекса по выражению - само выражение
+		"ARRAY(SELECT pg_get_indexdef(ix.indexrelid, k + 1, true) FROM generate_subscripts(ix.indkey, 1) k "+
+			"WHERE k < ix.indnkeyatts ORDER BY k)",
+	).From("pg_catalog.pg_index ix").
+		Join("pg_catalog.pg_class i ON i.oid = ix.indexrelid").
+		Join("pg_catalog.pg_class cl ON cl.oid = ix.indrelid").
+		Join("pg_catalog.pg_namespace n ON n.oid = cl.relnamespace").
+		Join("pg_catalog.pg_am am ON am.oid = i.relam").
+		Where(where).
+		OrderBy("n.nspname", "cl.relname", "i.relname").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tables := tableMap(tableList)
+	for rows.Next() {
+		var (
+			key tableKey
+			i   = new(Index)
+		)
+
+		if err = rows.Scan(&key.schema, &key.name, &i.Name, &i.IsUnique, &i.Method, &i.Predicate, pq.Array(&i.ColumnList)); err != nil {
+			return err
+		}
+
+		if t, ok := tables[key]; ok {
+			t.IndexList = append(t.IndexList, i)
+		}
+	}
+
+	return rows.Err()
+}
+
+// pgConstraintList читает ограничения UNIQUE и CHECK.
+func pgConstraintList(ctx context.Context, b sq.StatementBuilderType, where sq.Sqlizer, tableList []*Table) error {
+	rows, err := b.Select(
+		"n.nspname",
+		"cl.relname",
+		"c.conname",
+		"c.contype",
+		"COALESCE(pg_get_expr(c.conbin, c.conrelid, true), '')",
+		"ARRAY(SELECT a.attname FROM unnest(c.conkey) WITH ORDINALITY k(attnum, i) "+
+			"JOIN pg_catalog.pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum ORDER BY k.i)",
+	).From("pg_catalog.pg_constraint c").
+		Join("pg_catalog.pg_class cl ON cl.oid = c.conrelid").
+		Join("pg_catalog.pg_namespace n ON n.oid = cl.relnamespace").
+		Where("c.contype IN ('u', 'c')").
+		Where(where).
+		OrderBy("n.nspname", "cl.relname", "c.conname").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tables := tableMap(tableList)
+	for rows.Next() {
+		var (
+			key      tableKey
+			kind     string
+			c        = new(Constraint)
+			attnames []string
+		)
+
+		if err = rows.Scan(&key.schema, &key.name, &c.Name, &kind, &c.Expression, pq.Array(&attnames)); err != nil {
+			return err
+		}
+
+		t, ok := tables[key]
+		if !ok {
+			continue
+		}
+
+		if kind == "u" {
+			c.ColumnList = attnames
+			t.UniqueList = append(t.UniqueList, c)
+		} else {
+			t.CheckList = append(t.CheckList, c)
+		}
+	}
+
+	return rows.Err()
+}
+
+// pgActions - коды confdeltype и confupdtype.
+var pgActions = map[string]string{"a": NoAction, "r": Restrict, "c": Cascade, "n": SetNull, "d": SetDefault}
+
+func pgForeignKeyList(ctx context.Context, b sq.StatementBuilderType, where sq.Sqlizer, tableList []*Table) error {
+	//conkey и confkey перечисляют колонки попарно
+	const attnames = "ARRAY(SELECT a.attname FROM unnest(c.%[1]s) WITH ORDINALITY k(attnum, i) " +
+		"JOIN pg_catalog.pg_attribute a ON a.attrelid = c.%[2]s AND a.attnum = k.attnum ORDER BY k.i)"
+
+	rows, err := b.Select(
+		"n.nspname",
+		"cl.relname",
+		"c.conname",
+		"rn.nspname",
+		"rcl.relname",
+		"c.confdeltype",
+		"c.confupdtype",
+		fmt.Sprintf(attnames, "conkey", "conrelid"),
+		fmt.Sprintf(attnames, "confkey", "confrelid"),
+	).From("pg_catalog.pg_constraint c").
+		Join("pg_catalog.pg_class cl ON cl.oid = c.conrelid").
+		Join("pg_catalog.pg_namespace n ON n.oid = cl.relnamespace").
+		Join("pg_catalog.pg_class rcl ON rcl.oid = c.confrelid").
+		Join("pg_catalog.pg_namespace rn ON rn.oid = rcl.relnamespace").
+		Where("c.contype = 'f'").
+		Where(where).
+		OrderBy("n.nspname", "cl.relname", "c.conname").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tables := tableMap(tableList)
+	for rows.Next() {
+		var (
+			key                tableKey
+			fk                 = new(ForeignKey)
+			onDelete, onUpdate string
+		)
+
+		if err = rows.Scan(&key.schema, &key.name, &fk.Name, &fk.RefSchema, &fk.RefTable, &onDelete, &onUpdate,
+			pq.Array(&fk.ColumnList), pq.Array(&fk.RefColumnList)); err != nil {
+			return err
+		}
+
+		fk.OnDelete, fk.OnUpdate = pgActions[onDelete], pgActions[onUpdate]
+		if t, ok := tables[key]; ok {
+			t.FKList = append(t.FKList, fk)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (postgres) FunctionList(ctx context.Context, b sq.StatementBuilderType, nameList []string) ([]*Function, error) {
+	rows, err := b.
+		Select("r.routine_name", "r.specific_name", "r.data_type", "a.aggkind", "p.data_type").
+		From("information_schema.routines r").
+		Join("pg_catalog.pg_proc pr ON r.specific_name = pr.proname || '_' || pr.oid").
+		LeftJoin("pg_catalog.pg_aggregate a ON a.aggfnoid = pr.oid").
+		LeftJoin("information_schema.parameters p ON p.specific_name = r.specific_name AND p.parameter_mode = 'IN'").
+		Where(sq.Eq{"r.routine_name": nameList}).
+		OrderBy("r.routine_name", "r.specific_name", "p.ordinal_position").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	const anyType = "\"any\""
+
+	var (
+		functionList []*Function
+		lastF        *Function
+		lastS        *Signature
+		lastSName    string
+	)
+
+	for rows.Next() {
+		var (
+			f, sName, returnType string
+			aggKind, t           *string
+		)
+
+		if err = rows.Scan(&f, &sName, &returnType, &aggKind, &t); err != nil {
+			return nil, err
+		}
+
+		if lastF == nil || lastF.Name != f {
+			lastF = &Function{Name: f, Kind: Scalar}
+			functionList = append(functionList, lastF)
+		}
+
+		if aggKind != nil {
+			lastF.Kind = Aggregate
+			if *aggKind != "n" {
+				lastF.Kind = OrderedSet
+			}
+		}
+
+		if lastS == nil || lastSName != sName {
+			lastS, lastSName = &Signature{ReturnType: returnType}, sName
+			lastF.SignatureList = append(lastF.SignatureList, lastS)
+		}
+
+		if t == nil {
+			continue
+		}
+
+		if len(lastS.ArgTypeList) == 0 && *t != anyType {
+			lastF.TypeList = append(lastF.TypeList, *t)
+		}
+
+		lastS.ArgTypeList = append(lastS.ArgTypeList, *t)
+	}
+
+	return functionList, rows.Err()
+}
+
+// pgTypes - внутренние имена типов, которые lib/pq возвращает для колонок результата.
+var pgTypes = map[string]string{
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"bpchar":      "character",
+	"varchar":     "character varying",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+	`"any"`:       "any",
+}
+
+func (postgres) NormalizeType(t string) string {
+	t = strings.ToLower(t)
+	if n, ok := pgTypes[t]; ok {
+		return n
+	}
+	return t
+}
+
+func (postgres) BackendID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pid int64
+	err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	return pid, err
+}
+
+func (postgres) CancelBackend(ctx context.Context, db Execer, pid int64) error {
+	_, err := db.ExecContext(ctx, "SELECT pg_cancel_backend($1)", pid)
+	return err
+}
+
+// BeginConsole ограничивает время выполнения на стороне сервера, у остальных драйверов - только контекстом.
+func (postgres) BeginConsole(ctx context.Context, conn *sql.Conn, readOnly bool, timeout time.Duration) (*sql.Tx, error) {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout > 0 {
+		//SET не принимает параметры, значение - целое число миллисекунд
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+func (postgres) ConnError(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+
+	switch pqErr.Code {
+	case "28000", "28P01": //invalid_authorization_specification, invalid_password
+		return StepAuth
+	case "3D000": //invalid_catalog_name
+		return StepDatabase
+	}
+	return ""
+}
+
+// ServerInfo перечисляет атрибуты роли и права на текущую базу данных.
+func (postgres) ServerInfo(ctx context.Context, db Querier) (ServerInfo, error) {
+	var (
+		info ServerInfo
+		attr [7]bool
+	)
+
+	err := db.QueryRowContext(ctx, `SELECT version(), current_user,
+		r.rolsuper, r.rolcreatedb, r.rolcreaterole, r.rolreplication, r.rolbypassrls,
+		has_database_privilege(current_database(), 'CREATE'), has_database_privilege(current_database(), 'TEMPORARY')
+		FROM pg_roles r WHERE r.rolname = current_user`).
+		Scan(&info.Version, &info.User, &attr[0], &attr[1], &attr[2], &attr[3], &attr[4], &attr[5], &attr[6])
+	if err != nil {
+		return ServerInfo{}, err
+	}
+
+	for i, name := range [...]string{"SUPERUSER", "CREATEDB", "CREATEROLE", "REPLICATION", "BYPASSRLS", "CREATE", "TEMPORARY"} {
+		if attr[i] {
+			info.Privileges = append(info.Privileges, name)
+		}
+	}
+	return info, nil
+}
+
+func (postgres) ResetSession(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "DISCARD ALL")
+	return err
+}
@@ -0,0 +1,434 @@
+package dbmodel
+
+import (
+	"context"
+	"database/sql"
+	"datapoint/pkg/database"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sqlite совпадает с PostgreSQL в синтаксисе, кроме LIMIT и выборки.
+type sqlite struct {
+	postgres
+}
+
+func (sqlite) Driver() string {
+	return database.Sqlite3
+}
+
+func (sqlite) DSN(c Config) (string, error) {
+	if err := checkParams(SQLite, c.Params, sqliteParams); err != nil {
+		return "", err
+	}
+
+	//mode=rw не создаёт файл, если его нет
+	query := url.Values{"mode": {"rw"}}
+	if c.ReadOnly {
+		query.Set("mode", "ro")
+	}
+	for name, value := range c.Params {
+		query.Set(name, value)
+	}
+	return fmt.Sprintf("file:%s?%s", (&url.URL{Path: c.Path}).EscapedPath(), query.Encode()), nil
+}
+
+// CheckPath проверяет, что файл SQLite находится внутри каталога dir и не совпадает с файлами
+// из строк подключения forbidden, например с базой данных самого сервиса. Ссылки раскрываются.
+func (c *Config) CheckPath(dir string, forbidden ...string) error {
+	if c.Driver != SQLite {
+		return nil
+	}
+
+	if len(dir) == 0 {
+		return errors.New("подключение файлов SQLite выключено: не задан каталог sqlite.dir")
+	}
+
+	root, err := realPath(dir)
+	if err != nil {
+		return fmt.Errorf("не удалось определить каталог файлов SQLite: %s", err)
+	}
+
+	var path string
+	if path, err = realPath(c.Path); err != nil {
+		return fmt.Errorf("не удалось определить путь к файлу SQLite: %s", err)
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("файл SQLite должен находиться в каталоге %s", dir)
+	}
+
+	for _, dsn := range forbidden {
+		if p, err := realPath(sqlitePath(dsn)); err == nil && p == path {
+			return errors.New("файл SQLite совпадает с базой данных сервиса")
+		}
+	}
+
+	return nil
+}
+
+// realPath возвращает абсолютный путь без ссылок. Для несуществующего файла раскрывается
+// ближайший существующий каталог.
+func realPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var real string
+	if real, err = filepath.EvalSymlinks(path); err == nil {
+		return real, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) || filepath.Dir(path) == path {
+		return "", err
+	}
+
+	if real, err = realPath(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(path)), nil
+}
+
+// sqlitePath извлекает путь к файлу из строки подключения go-sqlite3.
+func sqlitePath(dsn string) string {
+	dsn = strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(dsn, '?'); i >= 0 {
+		dsn = dsn[:i]
+	}
+	return dsn
+}
+
+// sqliteParams - параметры go-sqlite3, которые не мешают режиму только для чтения.
+var sqliteParams = map[string]bool{
+	"_busy_timeout": true,
+	"_foreign_keys": true,
+	"_cache_size":   true,
+	"_synchronous":  true,
+	"_loc":          true,
+	"cache":         true,
+}
+
+func (sqlite) Placeholder() sq.PlaceholderFormat {
+	return sq.Question
+}
+
+// Limit подставляет LIMIT -1, если задан только OFFSET: SQLite не допускает OFFSET без LIMIT.
+func (sqlite) Limit(b sq.SelectBuilder, limit, offset uint64) sq.SelectBuilder {
+	if offset != 0 && limit == 0 {
+		return b.Suffix(fmt.Sprintf("LIMIT -1 OFFSET %d", offset))
+	}
+	return postgres{}.Limit(b, limit, offset)
+}
+
+func (sqlite) TableSample() bool {
+	return false
+}
+
+func (sqlite) SchemaList(context.Context, sq.StatementBuilderType) ([]string, error) {
+	return nil, nil
+}
+
+// TableList читает таблицы и представления файла через табличные функции pragma_table_info,
+// pragma_index_list и pragma_foreign_key_list. Определение представления - исходный CREATE VIEW из sqlite_master.
+func (sqlite) TableList(ctx context.Context, b sq.StatementBuilderType, _ []string, name string) ([]*Table, error) {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"c.table_name": name}
+	}
+
+	rows, err := b.Select(
+		"''",
+		"c.table_name",
+		"c.type",
+		"CASE WHEN c.type = 'view' THEN c.sql END",
+		"NULL",
+		"p.name",
+		"p.type",
+		//INTEGER PRIMARY KEY - синоним rowid и заполняется автоматически
+		"p.\"notnull\" AND p.dflt_value IS NULL AND NOT (p.pk = 1 AND lower(p.type) = 'integer') AND p.hidden = 0",
+		"p.pk > 0",
+		"p.dflt_value",
+		"p.pk = 1 AND lower(p.type) = 'integer'",
+		"false",
+		//2 и 3 - вычисляемые колонки; их выражение SQLite не сообщает
+		"p.hidden IN (2, 3)",
+		"NULL",
+		//длина и точность в SQLite не проверяются, поэтому не сообщаются
+		"NULL",
+		"NULL",
+		"NULL",
+		"NULL",
+		"NULL",
+		"NULL",
+	).From("(SELECT name AS table_name, type, sql FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%') c").
+		//в отличие от pragma_table_info, показывает вычисляемые колонки; 1 - скрытые колонки виртуальных таблиц
+		Join("pragma_table_xinfo(c.table_name) p").
+		Where("p.hidden <> 1").
+		Where(where).
+		OrderBy("c.table_name", "p.cid").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tableList, err := scanTableList(rows, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sqliteIndexList(ctx, b, name, tableList); err != nil {
+		return nil, err
+	}
+
+	if err = sqliteForeignKeyList(ctx, b, name, tableList); err != nil {
+		return nil, err
+	}
+
+	return tableList, nil
+}
+
+// sqliteForeignKeyList читает внешние ключи; у них нет имён, колонки одного ключа объединяет id.
+func sqliteForeignKeyList(ctx context.Context, b sq.StatementBuilderType, name string, tableList []*Table) error {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"m.name": name}
+	}
+
+	rows, err := b.Select(
+		"m.name",
+		"fk.id",
+		"fk.\"table\"",
+		"fk.on_delete",
+		"fk.on_update",
+		"fk.\"from\"",
+		//ссылка без списка колонок указывает на первичный ключ; pk - номер колонки в нём
+		"COALESCE(fk.\"to\", (SELECT r.name FROM pragma_table_info(fk.\"table\") r WHERE r.pk = fk.seq + 1), '')",
+	).From("sqlite_master m").
+		Join("pragma_foreign_key_list(m.name) fk").
+		Where("m.type = 'table' AND m.name NOT LIKE 'sqlite_%'").
+		Where(where).
+		OrderBy("m.name", "fk.id", "fk.seq").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var (
+		tables = tableMap(tableList)
+		lastFK *ForeignKey
+		lastT  *Table
+		lastID int
+	)
+
+	for rows.Next() {
+		var (
+			key               tableKey
+			id                int
+			fk                = new(ForeignKey)
+			column, refColumn string
+		)
+
+		if err = rows.Scan(&key.name, &id, &fk.RefTable, &fk.OnDelete, &fk.OnUpdate, &column, &refColumn); err != nil {
+			return err
+		}
+
+		t, ok := tables[key]
+		if !ok {
+			continue
+		}
+
+		if lastFK == nil || lastT != t || lastID != id {
+			lastFK, lastT, lastID = fk, t, id
+			t.FKList = append(t.FKList, fk)
+		}
+
+		lastFK.ColumnList = append(lastFK.ColumnList, column)
+		lastFK.RefColumnList = append(lastFK.RefColumnList, refColumn)
+	}
+
+	return rows.Err()
+}
+
+// sqliteIndexList читает индексы через pragma_index_list и pragma_index_info. Ограничения UNIQUE -
+// автоматические индексы с origin = 'u'. Ограничения CHECK SQLite хранит только в тексте CREATE TABLE,
+// поэтому они не читаются.
+func sqliteIndexList(ctx context.Context, b sq.StatementBuilderType, name string, tableList []*Table) error {
+	var where sq.Sqlizer
+	if name != "" {
+		where = sq.Eq{"m.name": name}
+	}
+
+	rows, err := b.Select(
+		"m.name",
+		"il.name",
+		"il.\"unique\"",
+		"il.origin",
+		"COALESCE(x.sql, '')",
+		"COALESCE(ii.name, '')", //NULL для выражения
+	).From("sqlite_master m").
+		Join("pragma_index_list(m.name) il").
+		Join("pragma_index_info(il.name) ii").
+		LeftJoin("sqlite_master x ON x.type = 'index' AND x.name = il.name").
+		Where("m.type = 'table' AND m.name NOT LIKE 'sqlite_%'").
+		Where(where).
+		OrderBy("m.name", "il.name", "ii.seqno").
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var (
+		tables = tableMap(tableList)
+		unique = make(map[*Index]bool) //индексы ограничений UNIQUE
+		lastI  *Index
+		lastT  *Table
+	)
+
+	for rows.Next() {
+		var (
+			key                    tableKey
+			i                      = &Index{Method: "btree"}
+			origin, create, column string
+		)
+
+		if err = rows.Scan(&key.name, &i.Name, &i.IsUnique, &origin, &create, &column); err != nil {
+			return err
+		}
+
+		t, ok := tables[key]
+		if !ok {
+			continue
+		}
+
+		if lastI == nil || lastT != t || lastI.Name != i.Name {
+			i.Predicate = partialPredicate(create)
+			lastI, lastT = i, t
+			t.IndexList = append(t.IndexList, i)
+			unique[i] = origin == "u"
+		}
+
+		lastI.ColumnList = append(lastI.ColumnList, column)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range tableList {
+		for _, i := range t.IndexList {
+			if unique[i] {
+				t.UniqueList = append(t.UniqueList, &Constraint{Name: i.Name, ColumnList: i.ColumnList})
+			}
+		}
+	}
+
+	return nil
+}
+
+var whereKeyword = regexp.MustCompile(`(?i)\sWHERE\s`)
+
+// partialPredicate выделяет условие частичного индекса из его CREATE INDEX.
+func partialPredicate(create string) string {
+	loc := whereKeyword.FindAllStringIndex(create, -1)
+	if len(loc) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(create[loc[len(loc)-1][1]:])
+}
+
+func (sqlite) FunctionList(_ context.Context, _ sq.StatementBuilderType, nameList []string) ([]*Function, error) {
+	return filterFunctionList(sqliteFunctionList, nameList), nil
+}
+
+// NormalizeType определяет тип по правилам родства типов SQLite: объявленный тип колонки произволен,
+// например VARCHAR(100) или UNSIGNED BIG INT.
+func (sqlite) NormalizeType(t string) string {
+	t = strings.ToLower(t)
+	switch {
+	case t == "" || t == "any":
+		return t
+	case strings.Contains(t, "int"):
+		return "integer"
+	case strings.Contains(t, "char"), strings.Contains(t, "clob"), strings.Contains(t, "text"):
+		return "text"
+	case strings.Contains(t, "blob"):
+		return "bytea"
+	case strings.Contains(t, "real"), strings.Contains(t, "floa"), strings.Contains(t, "doub"):
+		return "double precision"
+	case strings.Contains(t, "bool"):
+		return "boolean"
+	case t == "date":
+		return "date"
+	case strings.Contains(t, "datetime"), strings.Contains(t, "timestamp"):
+		return "timestamp without time zone"
+	}
+	return "numeric"
+}
+
+func (sqlite) BackendID(context.Context, *sql.Conn) (int64, error) {
+	return 0, nil
+}
+
+func (sqlite) CancelBackend(context.Context, Execer, int64) error {
+	return nil
+}
+
+// BeginConsole запрещает запись для сеанса: go-sqlite3 не поддерживает транзакции только для чтения.
+func (sqlite) BeginConsole(ctx context.Context, conn *sql.Conn, readOnly bool, _ time.Duration) (*sql.Tx, error) {
+	if readOnly {
+		if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+}
+
+func (sqlite) ResetSession(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "PRAGMA query_only = OFF")
+	return err
+}
+
+// ConnError относит любую ошибку к файлу: у SQLite нет сервера и аутентификации.
+func (sqlite) ConnError(error) string {
+	return StepDatabase
+}
+
+func (sqlite) ServerInfo(ctx context.Context, db Querier) (ServerInfo, error) {
+	var info ServerInfo
+	err := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&info.Version)
+	return info, err
+}
+
+// sqliteFunctionList - встроенные функции SQLite. Типы колонок в SQLite произвольны, поэтому не проверяются.
+var sqliteFunctionList = []*Function{
+	{Name: "avg", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "double precision"}}},
+	{Name: "count", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "integer"}}},
+	{Name: "max", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "any"}}},
+	{Name: "min", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "any"}}},
+	{Name: "sum", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "any"}}},
+	{Name: "total", Kind: Aggregate, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "double precision"}}},
+	{Name: "group_concat", Kind: Aggregate, SignatureList: []*Signature{
+		{ArgTypeList: []string{"any"}, ReturnType: "text"},
+		{ArgTypeList: []string{"any", "text"}, ReturnType: "text"},
+	}},
+	{Name: "lower", Kind: Scalar, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "text"}}},
+	{Name: "upper", Kind: Scalar, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "text"}}},
+	{Name: "length", Kind: Scalar, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "integer"}}},
+	{Name: "abs", Kind: Scalar, SignatureList: []*Signature{{ArgTypeList: []string{"any"}, ReturnType: "any"}}},
+	{Name: "round", Kind: Scalar, SignatureList: []*Signature{
+		{ArgTypeList: []string{"any"}, ReturnType: "double precision"},
+		{ArgTypeList: []string{"any", "integer"}, ReturnType: "double precision"},
+	}},
+}
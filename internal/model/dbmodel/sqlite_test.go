@@ -0,0 +1,205 @@
+package dbmodel_test
+
+import (
+	"context"
+	"database/sql"
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/querymodel"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "example.db")
+
+	file, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("не удалось создать файл SQLite: %s", err)
+	}
+
+	for _, query := range [...]string{
+		"CREATE TABLE customer (id INTEGER PRIMARY KEY, name TEXT NOT NULL, note TEXT, UNIQUE (name, note))",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customer, total REAL)",
+		"INSERT INTO customer (name, note) VALUES ('Анна', 'постоянный'), ('Борис', NULL)",
+		"INSERT INTO orders (customer_id, total) VALUES (1, 10.5), (1, 4.5), (2, 7)",
+		"CREATE VIEW big_orders AS SELECT id, total FROM orders WHERE total > 5",
+		"CREATE INDEX orders_big ON orders (customer_id, total) WHERE total > 5",
+		"CREATE TABLE country (code TEXT PRIMARY KEY)",
+		"CREATE TABLE region (country TEXT, code TEXT, PRIMARY KEY (country, code))",
+		//country входит в два внешних ключа, ссылка на region - составная и без списка колонок
+		"CREATE TABLE office (id INTEGER PRIMARY KEY, country TEXT REFERENCES country ON DELETE CASCADE, region TEXT, " +
+			"FOREIGN KEY (country, region) REFERENCES region)",
+		"CREATE TABLE item (id INTEGER PRIMARY KEY, price REAL NOT NULL DEFAULT 0, qty INTEGER NOT NULL, " +
+			"total REAL NOT NULL GENERATED ALWAYS AS (price * qty))",
+	} {
+		if _, err = file.Exec(query); err != nil {
+			t.Fatalf("%s --> %s", query, err)
+		}
+	}
+	_ = file.Close()
+
+	db, err := dbmodel.New(dbmodel.Info{
+		Name:   "sqlite",
+		Config: dbmodel.Config{Driver: dbmodel.SQLite, Path: path, ReadOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("не удалось открыть файл SQLite: %s", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	tableList, err := db.TableList(ctx)
+	if err != nil {
+		t.Fatalf("не удалось получить список таблиц: %s", err)
+	}
+
+	columns := make(map[string]*dbmodel.Column)
+	kinds := make(map[string]*dbmodel.Table)
+	for _, table := range tableList {
+		kinds[table.Name] = table
+		for _, c := range table.ColumnList {
+			columns[table.Name+"."+c.Name] = c
+		}
+	}
+
+	if c := columns["customer.id"]; c == nil || !c.IsPK || c.IsRequired || c.Type != "integer" {
+		t.Errorf("customer.id --> ожидался необязательный первичный ключ integer, получено: %+v", c)
+	}
+
+	if c := columns["customer.name"]; c == nil || !c.IsRequired {
+		t.Errorf("customer.name --> ожидалась обязательная колонка, получено: %+v", c)
+	}
+
+	if c := columns["orders.customer_id"]; c == nil || c.FK == nil || c.FK.TableName != "customer" || c.FK.ColumnName != "id" {
+		t.Errorf("orders.customer_id --> ожидался внешний ключ на customer.id, получено: %+v", c)
+	}
+
+	if v := kinds["big_orders"]; v == nil || v.Kind != dbmodel.KindView || !strings.Contains(v.Definition, "total > 5") {
+		t.Errorf("big_orders --> ожидалось представление с определением, получено: %+v", v)
+	}
+
+	if o := kinds["orders"]; o == nil || o.Kind != dbmodel.KindTable || o.Definition != "" {
+		t.Errorf("orders --> ожидалась таблица, получено: %+v", o)
+	}
+
+	if c := kinds["customer"]; c == nil || len(c.UniqueList) != 1 || strings.Join(c.UniqueList[0].ColumnList, ",") != "name,note" {
+		t.Errorf("customer --> ожидалось ограничение UNIQUE (name, note), получено: %+v", c)
+	}
+
+	if o := kinds["orders"]; o == nil || len(o.IndexList) != 1 {
+		t.Errorf("orders --> ожидался один индекс, получено: %+v", o)
+	} else if i := o.IndexList[0]; i.Name != "orders_big" || i.IsUnique || i.Predicate != "total > 5" ||
+		strings.Join(i.ColumnList, ",") != "customer_id,total" {
+		t.Errorf("orders --> ожидался частичный индекс orders_big (customer_id, total), получено: %+v", i)
+	}
+
+	office := kinds["office"]
+	if office == nil || len(office.FKList) != 2 {
+		t.Fatalf("office --> ожидалось два внешних ключа, получено: %+v", office)
+	}
+
+	fkList := make(map[string]*dbmodel.ForeignKey)
+	for _, fk := range office.FKList {
+		fkList[fk.RefTable] = fk
+	}
+
+	if fk := fkList["country"]; fk == nil || strings.Join(fk.ColumnList, ",") != "country" ||
+		strings.Join(fk.RefColumnList, ",") != "code" || fk.OnDelete != dbmodel.Cascade {
+		t.Errorf("office --> ожидался внешний ключ country -> country.code ON DELETE CASCADE, получено: %+v", fk)
+	}
+
+	if fk := fkList["region"]; fk == nil || strings.Join(fk.ColumnList, ",") != "country,region" ||
+		strings.Join(fk.RefColumnList, ",") != "country,code" || fk.OnDelete != dbmodel.NoAction {
+		t.Errorf("office --> ожидался внешний ключ (country, region) -> region (country, code), получено: %+v", fk)
+	}
+
+	if c := columns["office.region"]; c == nil || c.FK != nil {
+		t.Errorf("office.region --> колонка составного внешнего ключа не должна иметь FK, получено: %+v", c)
+	}
+
+	if c := columns["item.id"]; c == nil || !c.IsIdentity || c.IsGenerated {
+		t.Errorf("item.id --> ожидалась автоматически заполняемая колонка, получено: %+v", c)
+	}
+
+	if c := columns["item.price"]; c == nil || c.IsRequired || c.Default != "0" {
+		t.Errorf("item.price --> ожидалась необязательная колонка со значением по умолчанию 0, получено: %+v", c)
+	}
+
+	if c := columns["item.total"]; c == nil || !c.IsGenerated || c.IsRequired {
+		t.Errorf("item.total --> ожидалась необязательная вычисляемая колонка, получено: %+v", c)
+	}
+
+	if _, err = db.TableByName(ctx, "orders"); err != nil {
+		t.Errorf("не удалось получить таблицу по имени: %s", err)
+	}
+
+	metadata, err := db.Metadata(ctx)
+	if err != nil {
+		t.Fatalf("не удалось получить метаданные: %s", err)
+	}
+
+	if again, _ := db.Metadata(ctx); again == nil || again.ETag != metadata.ETag {
+		t.Errorf("ожидался одинаковый ETag у неизменившихся метаданных, получено: %s и %+v", metadata.ETag, again)
+	}
+
+	if o, err := metadata.TableByName("orders"); err != nil || o.Name != "orders" {
+		t.Errorf("orders --> ожидалась таблица из метаданных, получено: %+v, %v", o, err)
+	}
+
+	if _, err = metadata.TableByName("missing"); err == nil {
+		t.Errorf("missing --> ожидалась ошибка для несуществующей таблицы")
+	}
+
+	if _, err = db.ExecContext(ctx, "DELETE FROM orders"); err == nil {
+		t.Errorf("ожидалась ошибка записи в файл, открытый только для чтения")
+	}
+
+	functionList, err := db.FunctionList(ctx)
+	if err != nil {
+		t.Fatalf("не удалось получить список функций: %s", err)
+	}
+
+	customer := querymodel.TableKey{Name: "customer"}
+	info := querymodel.Info{
+		Type: querymodel.Select,
+		Table: &querymodel.Table{
+			TableKey: customer,
+			Next: []*querymodel.Table{{
+				TableKey: querymodel.TableKey{Name: "orders"},
+				Rule: &querymodel.Rule{
+					Type: querymodel.Join,
+					Conditions: []*querymodel.Condition{{
+						Columns: [2]*querymodel.Column{
+							{TableKey: customer, Column: dbmodel.Column{Name: "id"}},
+							{TableKey: querymodel.TableKey{Name: "orders"}, Column: dbmodel.Column{Name: "customer_id"}},
+						},
+						Operator: querymodel.Equal,
+					}},
+				},
+			}},
+		},
+		Columns: []*querymodel.Column{
+			{TableKey: customer, Column: dbmodel.Column{Name: "name"}},
+			{TableKey: querymodel.TableKey{Name: "orders"}, Column: dbmodel.Column{Name: "total"}, Function: "sum"},
+		},
+		OrderBy: []*querymodel.Column{
+			{TableKey: customer, Column: dbmodel.Column{Name: "name"}},
+		},
+		Offset: 1,
+	}
+
+	if err = info.Resolve(tableList, functionList); err != nil {
+		t.Fatalf("запрос не прошёл проверку: %s", err)
+	}
+
+	result, err := querymodel.New(info, db.B(), db.Dialect()).Execute(ctx, db)
+	if err != nil {
+		t.Fatalf("не удалось выполнить запрос: %s", err)
+	}
+
+	if result.RowCount != 1 || *result.Data[0]["customer.name"].(*any) != "Борис" {
+		t.Errorf("ожидалась одна строка с Борис, получено: %+v", result.Data)
+	}
+}
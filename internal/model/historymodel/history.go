@@ -0,0 +1,38 @@
+package historymodel
+
+import (
+	"datapoint/internal/model/querymodel"
+	"errors"
+	"time"
+)
+
+// ErrRedacted - запрос сохранён без значений и не может быть выполнен повторно.
+var ErrRedacted = errors.New("значения запроса скрыты настройкой истории, повторить его нельзя")
+
+// Raw - тип записи для произвольного SQL из консоли.
+const Raw = "raw"
+
+type Entry struct {
+	ID        string
+	DBID      string
+	Info      querymodel.Info
+	Query     string
+	Raw       bool  //произвольный SQL из консоли: Info пустой, запрос есть только в Query
+	Args      []any //nil, если аргументы скрыты
+	Redacted  bool  //значения из Info и аргументы не сохранены, поэтому запрос нельзя повторить
+	Duration  time.Duration
+	RowCount  int64
+	Error     string
+	Caller    string
+	CreatedAt time.Time
+}
+
+type Filter struct {
+	DBID   string
+	Type   string
+	Failed *bool
+	From   *time.Time
+	To     *time.Time
+	Limit  uint64
+	Offset uint64
+}
@@ -0,0 +1,40 @@
+package jobmodel
+
+import (
+	"datapoint/internal/model/querymodel"
+	"errors"
+	"time"
+)
+
+const (
+	Queued  = "queued"
+	Running = "running"
+	Done    = "done"
+	Failed  = "failed"
+)
+
+var (
+	ErrNotFound = errors.New("задания не существует")
+	ErrNotDone  = errors.New("задание ещё не выполнено")
+)
+
+type Job struct {
+	ID         string
+	DBID       string
+	Info       querymodel.Info
+	Status     string
+	Error      string
+	RowCount   int64
+	Truncated  bool //в результате сохранено только console.row_limit строк
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	ExpiresAt  *time.Time //результат удаляется после этого момента
+}
+
+type Page struct {
+	Data     []map[string]any
+	RowCount int64
+	Limit    uint64
+	Offset   uint64
+}
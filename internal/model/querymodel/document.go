@@ -0,0 +1,471 @@
+package querymodel
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Document - внешнее представление запроса, которое сохраняется и передаётся клиентам.
+// При изменении формата версия увеличивается, а для предыдущей добавляется функция в upgrades.
+type Document struct {
+	Version int           `json:"version"`
+	Type    string        `json:"type"`
+	Table   *DocTable     `json:"table"`
+	Columns []*DocColumn  `json:"columns,omitempty"`
+	Filters []*DocFilter  `json:"filters,omitempty"`
+	OrderBy []*DocOrderBy `json:"orderBy,omitempty"`
+	Limit   uint64        `json:"limit,omitempty"`
+	Offset  uint64        `json:"offset,omitempty"`
+	Sample  *DocSample    `json:"sample,omitempty"`
+}
+
+const Version = 2
+
+//go:embed schema.json
+var Schema []byte
+
+type DocTable struct {
+	Schema string      `json:"schema,omitempty"`
+	Name   string      `json:"name"`
+	Index  uint8       `json:"index,omitempty"` //номер повторного вхождения таблицы в запрос
+	Join   *DocJoin    `json:"join,omitempty"`
+	Joins  []*DocTable `json:"joins,omitempty"`
+}
+
+type DocJoin struct {
+	Type string          `json:"type"`
+	On   []*DocCondition `json:"on"`
+}
+
+type DocCondition struct {
+	Left     DocColumnRef `json:"left"`
+	Operator string       `json:"operator"`
+	Right    DocColumnRef `json:"right"`
+}
+
+type DocColumnRef struct {
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table,omitempty"`
+	Index  uint8  `json:"index,omitempty"`
+	Column string `json:"column"`
+}
+
+type DocFunction struct {
+	Name     string `json:"name"`
+	Args     []any  `json:"args,omitempty"`
+	ArgIndex int    `json:"argIndex,omitempty"`
+}
+
+type DocColumn struct {
+	DocColumnRef
+	Function *DocFunction `json:"function,omitempty"`
+	Value    any          `json:"value,omitempty"`
+}
+
+type DocFilter struct {
+	DocColumnRef
+	Value any `json:"value"`
+}
+
+type DocOrderBy struct {
+	DocColumnRef
+	Function *DocFunction `json:"function,omitempty"`
+	Desc     bool         `json:"desc,omitempty"`
+	Nulls    string       `json:"nulls,omitempty"`
+	Collate  string       `json:"collate,omitempty"`
+	ByAlias  bool         `json:"byAlias,omitempty"`
+}
+
+type DocSample struct {
+	Method  string  `json:"method,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+	Rows    uint64  `json:"rows,omitempty"`
+	Seed    *int64  `json:"seed,omitempty"`
+}
+
+// upgrades[v] переводит документ версии v в версию v+1.
+var upgrades = map[int]func([]byte) ([]byte, error){
+	1: upgradeV1,
+}
+
+// v1Info и связанные типы повторяют querymodel.Info в том виде, в котором он сериализовался в первой версии.
+// Они заморожены: изменения текущих типов не должны влиять на чтение старых документов.
+type v1Info struct {
+	Type    string
+	Table   *v1Table
+	Columns []*v1Column
+	OrderBy []*v1Column
+	Where   []*v1Column
+	Limit   uint64
+	Offset  uint64
+}
+
+type v1TableKey struct {
+	Name      string
+	Increment uint8
+}
+
+type v1Table struct {
+	v1TableKey
+	Next []*v1Table
+	Rule *v1Rule
+}
+
+type v1Rule struct {
+	Type       string
+	Conditions []*v1Condition
+}
+
+type v1Condition struct {
+	Columns  [2]*v1Column
+	Operator string
+}
+
+type v1FK struct {
+	TableName  string
+	ColumnName string
+}
+
+type v1Column struct {
+	Name       string
+	Type       string
+	IsRequired bool
+	IsPK       bool
+	FK         *v1FK
+	TableKey   v1TableKey
+	Function   string
+	Desc       bool
+	Value      any
+}
+
+// upgradeV1 переводит запрос из первой версии, в которой querymodel.Info сериализовался без тегов.
+func upgradeV1(data []byte) ([]byte, error) {
+	var info v1Info
+	if err := strictUnmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	d := Document{
+		Version: 2,
+		Type:    info.Type,
+		Table:   info.Table.doc(),
+		Limit:   info.Limit,
+		Offset:  info.Offset,
+	}
+
+	for _, c := range info.Columns {
+		d.Columns = append(d.Columns, &DocColumn{DocColumnRef: c.ref(), Function: c.function(), Value: c.Value})
+	}
+
+	for _, c := range info.Where {
+		d.Filters = append(d.Filters, &DocFilter{DocColumnRef: c.ref(), Value: c.Value})
+	}
+
+	for _, c := range info.OrderBy {
+		d.OrderBy = append(d.OrderBy, &DocOrderBy{DocColumnRef: c.ref(), Function: c.function(), Desc: c.Desc})
+	}
+
+	return json.Marshal(d)
+}
+
+func (t *v1Table) doc() *DocTable {
+	if t == nil {
+		return nil
+	}
+
+	dt := &DocTable{Name: t.Name, Index: t.Increment}
+
+	if t.Rule != nil {
+		dt.Join = &DocJoin{Type: t.Rule.Type}
+		for _, c := range t.Rule.Conditions {
+			dt.Join.On = append(dt.Join.On, &DocCondition{
+				Left:     c.Columns[0].ref(),
+				Operator: c.Operator,
+				Right:    c.Columns[1].ref(),
+			})
+		}
+	}
+
+	for _, n := range t.Next {
+		dt.Joins = append(dt.Joins, n.doc())
+	}
+
+	return dt
+}
+
+func (c *v1Column) ref() DocColumnRef {
+	if c == nil {
+		return DocColumnRef{}
+	}
+	return DocColumnRef{Table: c.TableKey.Name, Index: c.TableKey.Increment, Column: c.Name}
+}
+
+func (c *v1Column) function() *DocFunction {
+	if c.Function == "" {
+		return nil
+	}
+	return &DocFunction{Name: c.Function}
+}
+
+func strictUnmarshal(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	d.UseNumber()
+
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+
+	if d.More() {
+		return errors.New("после документа есть лишние данные")
+	}
+
+	return nil
+}
+
+// Decode разбирает документ любой поддерживаемой версии; документ без версии считается первой версией.
+func Decode(data []byte) (Info, error) {
+	var header struct {
+		Version *int `json:"version"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return Info{}, err
+	}
+
+	version := 1
+	if header.Version != nil {
+		version = *header.Version
+	}
+
+	if version < 1 || version > Version {
+		return Info{}, fmt.Errorf("неподдерживаемая версия документа: %d", version)
+	}
+
+	for ; version < Version; version++ {
+		var err error
+		if data, err = upgrades[version](data); err != nil {
+			return Info{}, fmt.Errorf("не удалось обновить документ версии %d: %s", version, err)
+		}
+	}
+
+	if err := validateSchema(data); err != nil {
+		return Info{}, err
+	}
+
+	var d Document
+	if err := strictUnmarshal(data, &d); err != nil {
+		return Info{}, err
+	}
+
+	return d.Info()
+}
+
+func Encode(info Info) ([]byte, error) {
+	return json.Marshal(NewDocument(info))
+}
+
+func NewDocument(info Info) Document {
+	d := Document{
+		Version: Version,
+		Type:    info.Type,
+		Table:   newDocTable(info.Table),
+		Limit:   info.Limit,
+		Offset:  info.Offset,
+	}
+
+	if info.Sample != nil {
+		d.Sample = &DocSample{
+			Method:  info.Sample.Method,
+			Percent: info.Sample.Percent,
+			Rows:    info.Sample.Rows,
+			Seed:    info.Sample.Seed,
+		}
+	}
+
+	for _, c := range info.Columns {
+		d.Columns = append(d.Columns, &DocColumn{
+			DocColumnRef: newDocColumnRef(c),
+			Function:     newDocFunction(c),
+			Value:        c.Value,
+		})
+	}
+
+	for _, c := range info.Where {
+		d.Filters = append(d.Filters, &DocFilter{
+			DocColumnRef: newDocColumnRef(c),
+			Value:        c.Value,
+		})
+	}
+
+	for _, c := range info.OrderBy {
+		d.OrderBy = append(d.OrderBy, &DocOrderBy{
+			DocColumnRef: newDocColumnRef(c),
+			Function:     newDocFunction(c),
+			Desc:         c.Desc,
+			Nulls:        c.Nulls,
+			Collate:      c.Collate,
+			ByAlias:      c.ByAlias,
+		})
+	}
+
+	return d
+}
+
+func newDocTable(t *Table) *DocTable {
+	if t == nil {
+		return nil
+	}
+
+	dt := &DocTable{Schema: t.Schema, Name: t.Name, Index: t.Increment}
+
+	if t.Rule != nil {
+		dt.Join = &DocJoin{Type: t.Rule.Type}
+		for _, c := range t.Rule.Conditions {
+			dt.Join.On = append(dt.Join.On, &DocCondition{
+				Left:     newDocColumnRef(c.Columns[0]),
+				Operator: c.Operator,
+				Right:    newDocColumnRef(c.Columns[1]),
+			})
+		}
+	}
+
+	for _, n := range t.Next {
+		dt.Joins = append(dt.Joins, newDocTable(n))
+	}
+
+	return dt
+}
+
+func newDocColumnRef(c *Column) DocColumnRef {
+	if c == nil {
+		return DocColumnRef{}
+	}
+	return DocColumnRef{Schema: c.TableKey.Schema, Table: c.TableKey.Name, Index: c.TableKey.Increment, Column: c.Name}
+}
+
+func newDocFunction(c *Column) *DocFunction {
+	if len(c.Function) == 0 {
+		return nil
+	}
+	return &DocFunction{Name: c.Function, Args: c.Args, ArgIndex: c.ArgIndex}
+}
+
+func (d Document) Info() (Info, error) {
+	if d.Table == nil {
+		return Info{}, errors.New("не указана таблица")
+	}
+
+	info := Info{
+		Type:   d.Type,
+		Limit:  d.Limit,
+		Offset: d.Offset,
+	}
+
+	if d.Sample != nil {
+		info.Sample = &Sample{
+			Method:  d.Sample.Method,
+			Percent: d.Sample.Percent,
+			Rows:    d.Sample.Rows,
+			Seed:    d.Sample.Seed,
+		}
+	}
+
+	var err error
+	if info.Table, err = d.Table.table(); err != nil {
+		return Info{}, err
+	}
+
+	for _, c := range d.Columns {
+		column := c.column()
+		column.Value = normalize(c.Value)
+		c.Function.apply(column)
+		info.Columns = append(info.Columns, column)
+	}
+
+	for _, c := range d.Filters {
+		column := c.column()
+		column.Value = normalize(c.Value)
+		info.Where = append(info.Where, column)
+	}
+
+	for _, c := range d.OrderBy {
+		column := c.column()
+		c.Function.apply(column)
+		column.Desc, column.Nulls, column.Collate, column.ByAlias = c.Desc, c.Nulls, c.Collate, c.ByAlias
+		info.OrderBy = append(info.OrderBy, column)
+	}
+
+	return info, nil
+}
+
+func (dt *DocTable) table() (*Table, error) {
+	if dt == nil {
+		return nil, nil
+	}
+
+	t := &Table{TableKey: TableKey{Schema: dt.Schema, Name: dt.Name, Increment: dt.Index}}
+
+	if dt.Join != nil {
+		t.Rule = &Rule{Type: dt.Join.Type}
+		for _, c := range dt.Join.On {
+			t.Rule.Conditions = append(t.Rule.Conditions, &Condition{
+				Columns:  [2]*Column{c.Left.column(), c.Right.column()},
+				Operator: c.Operator,
+			})
+		}
+	}
+
+	for _, n := range dt.Joins {
+		if n.Join == nil {
+			return nil, fmt.Errorf("для таблицы %s не указано условие соединения", n.Name)
+		}
+
+		next, err := n.table()
+		if err != nil {
+			return nil, err
+		}
+		t.Next = append(t.Next, next)
+	}
+
+	return t, nil
+}
+
+func (r DocColumnRef) column() *Column {
+	c := &Column{TableKey: TableKey{Schema: r.Schema, Name: r.Table, Increment: r.Index}}
+	c.Name = r.Column
+	return c
+}
+
+func (f *DocFunction) apply(c *Column) {
+	if f == nil {
+		return
+	}
+
+	c.Function, c.ArgIndex = f.Name, f.ArgIndex
+	for _, a := range f.Args {
+		c.Args = append(c.Args, normalize(a))
+	}
+}
+
+// normalize заменяет json.Number, полученные при строгом разборе, на int64 или float64.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	}
+
+	return v
+}
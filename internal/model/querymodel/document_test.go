@@ -0,0 +1,169 @@
+package querymodel
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"reflect"
+	"testing"
+)
+
+var info = Info{
+	Type: Select,
+	Table: &Table{
+		TableKey: table.TableKey,
+		Next: []*Table{{
+			TableKey: TableKey{Schema: "audit", Name: table.Name, Increment: 1},
+			Rule: &Rule{
+				Type: Left,
+				Conditions: []*Condition{{
+					Columns: [2]*Column{
+						{Column: dbmodel.Column{Name: "id"}, TableKey: table.TableKey},
+						{Column: dbmodel.Column{Name: "id"}, TableKey: TableKey{Schema: "audit", Name: table.Name, Increment: 1}},
+					},
+					Operator: Equal,
+				}},
+			},
+		}},
+	},
+	Columns: []*Column{
+		{Column: dbmodel.Column{Name: "name"}, TableKey: table.TableKey},
+		{Column: dbmodel.Column{Name: "age"}, TableKey: table.TableKey, Function: "round", Args: []any{int64(2)}},
+	},
+	Where: []*Column{
+		{Column: dbmodel.Column{Name: "name"}, TableKey: table.TableKey, Value: "qtbbt"},
+	},
+	OrderBy: []*Column{
+		{Column: dbmodel.Column{Name: "name"}, TableKey: table.TableKey, Desc: true, Nulls: NullsLast},
+	},
+	Limit:  10,
+	Sample: &Sample{Method: SampleSystem, Percent: 10, Seed: new(int64)},
+}
+
+func TestDocument(t *testing.T) {
+	data, err := Encode(info)
+	if err != nil {
+		t.Fatalf("не удалось закодировать запрос: %s", err)
+	}
+
+	var decoded Info
+	if decoded, err = Decode(data); err != nil {
+		t.Fatalf("не удалось разобрать документ: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, info) {
+		t.Errorf("ожидалось: %+v, получено: %+v", info, decoded)
+	}
+}
+
+func TestDecodeV1(t *testing.T) {
+	//первая версия - querymodel.Info, сериализованный без тегов
+	const data = `{
+		"Type": "select",
+		"Table": {
+			"Name": "example", "Increment": 0,
+			"Next": [{
+				"Name": "example", "Increment": 1, "Next": null,
+				"Rule": {"Type": "left", "Conditions": [{"Columns": [
+					{"Name": "id", "Type": "", "IsRequired": false, "IsPK": false, "FK": null, "TableKey": {"Name": "example", "Increment": 0}, "Function": "", "Desc": false, "Value": null},
+					{"Name": "id", "Type": "", "IsRequired": false, "IsPK": false, "FK": null, "TableKey": {"Name": "example", "Increment": 1}, "Function": "", "Desc": false, "Value": null}
+				], "Operator": "="}]}
+			}],
+			"Rule": null
+		},
+		"Columns": [
+			{"Name": "name", "Type": "", "IsRequired": false, "IsPK": false, "FK": null, "TableKey": {"Name": "example", "Increment": 0}, "Function": "", "Desc": false, "Value": null},
+			{"Name": "age", "Type": "", "IsRequired": false, "IsPK": false, "FK": null, "TableKey": {"Name": "example", "Increment": 0}, "Function": "count", "Desc": false, "Value": null}
+		],
+		"OrderBy": [
+			{"Name": "name", "Type": "", "IsRequired": false, "IsPK": false, "FK": null, "TableKey": {"Name": "example", "Increment": 0}, "Function": "", "Desc": true, "Value": null}
+		],
+		"Where": [
+			{"Name": "name", "Type": "", "IsRequired": false, "IsPK": false, "FK": null, "TableKey": {"Name": "example", "Increment": 0}, "Function": "", "Desc": false, "Value": "qtbbt"}
+		],
+		"Limit": 10,
+		"Offset": 0
+	}`
+
+	joined := TableKey{Name: table.Name, Increment: 1}
+	expected := Info{
+		Type: Select,
+		Table: &Table{
+			TableKey: table.TableKey,
+			Next: []*Table{{
+				TableKey: joined,
+				Rule: &Rule{
+					Type: Left,
+					Conditions: []*Condition{{
+						Columns: [2]*Column{
+							{Column: dbmodel.Column{Name: "id"}, TableKey: table.TableKey},
+							{Column: dbmodel.Column{Name: "id"}, TableKey: joined},
+						},
+						Operator: Equal,
+					}},
+				},
+			}},
+		},
+		Columns: []*Column{
+			{Column: dbmodel.Column{Name: "name"}, TableKey: table.TableKey},
+			{Column: dbmodel.Column{Name: "age"}, TableKey: table.TableKey, Function: "count"},
+		},
+		Where: []*Column{
+			{Column: dbmodel.Column{Name: "name"}, TableKey: table.TableKey, Value: "qtbbt"},
+		},
+		OrderBy: []*Column{
+			{Column: dbmodel.Column{Name: "name"}, TableKey: table.TableKey, Desc: true},
+		},
+		Limit: 10,
+	}
+
+	decoded, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatalf("не удалось разобрать документ: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("ожидалось: %+v, получено: %+v", expected, decoded)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := [...]string{
+		`{"version": 2, "type": "select", "table": {"name": "example"}, "unknown": 1}`,
+		`{"version": 2, "type": "select", "table": {"name": "example", "joins": [{"name": "example", "index": 1}]}}`,
+		`{"version": 2, "type": "select"}`,
+		`{"version": 3, "type": "select", "table": {"name": "example"}}`,
+		`{"Type": "select", "Unknown": 1}`,
+		`{"version": 2, "type": "select", "table": {"name": "example"}, "sample": {"method": "SYSTEM", "percent": 0}}`,
+	}
+
+	for _, test := range tests {
+		if _, err := Decode([]byte(test)); err == nil {
+			t.Errorf("%s --> ожидалась ошибка", test)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	rows := info
+	rows.Sample = &Sample{Rows: 100}
+
+	for _, test := range [...]Info{info, rows} {
+		data, err := Encode(test)
+		if err != nil {
+			t.Fatalf("не удалось закодировать запрос: %s", err)
+		}
+
+		if err = validateSchema(data); err != nil {
+			t.Errorf("%s --> %s", data, err)
+		}
+	}
+
+	//проверка самого валидатора: лишнее поле и значение не по схеме должны отклоняться
+	for _, test := range [...]string{
+		`{"version": 2, "type": "select", "table": {"name": "example"}, "unknown": 1}`,
+		`{"version": 2, "type": "select", "table": {"name": "example"}, "sample": {"percent": 0}}`,
+	} {
+		if validateSchema([]byte(test)) == nil {
+			t.Errorf("%s --> документ прошёл проверку схемы", test)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package querymodel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenParam
+	tokenSymbol
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// is сообщает, совпадает ли токен с ключевым словом или символом без учёта регистра.
+func (t token) is(s string) bool {
+	return (t.kind == tokenIdent || t.kind == tokenSymbol) && strings.EqualFold(t.value, s)
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "конец запроса"
+	case tokenString:
+		return fmt.Sprintf("'%s'", t.value)
+	case tokenQuotedIdent:
+		return fmt.Sprintf(`"%s"`, t.value)
+	default:
+		return t.value
+	}
+}
+
+type ParseError struct {
+	Pos         int
+	Message     string
+	Unsupported bool
+}
+
+func (e *ParseError) Error() string {
+	if e.Unsupported {
+		return fmt.Sprintf("позиция %d: не поддерживается %s", e.Pos, e.Message)
+	}
+	return fmt.Sprintf("позиция %d: %s", e.Pos, e.Message)
+}
+
+var symbols = [...]string{"<>", "!=", "<=", ">=", "::", "||", ",", ".", "(", ")", "*", "=", "<", ">", ";", "+", "-", "/", "%", "[", "]"}
+
+// mysqlSymbols проверяются раньше symbols, чтобы <=> не разбирался как <= и >.
+var mysqlSymbols = [...]string{"<=>", ":=", "&&", "<<", ">>", "@", "&", "|", "^", "~", "!"}
+
+// lex разбивает SQL на токены, пропуская пробелы и комментарии. Позиции считаются в рунах с единицы.
+func lex(sql string) ([]token, error) {
+	return lexDialect(sql, false)
+}
+
+// lexDialect разбирает SQL по правилам PostgreSQL или, если mysql, MySQL: строки в одинарных и двойных кавычках
+// с escape-последовательностями, идентификаторы в обратных кавычках, комментарии # и -- только с пробелом после.
+func lexDialect(sql string, mysql bool) ([]token, error) {
+	var (
+		r      = []rune(sql)
+		tokens []token
+	)
+
+	for i := 0; i < len(r); {
+		switch {
+		case unicode.IsSpace(r[i]):
+			i++
+		case mysql && r[i] == '#',
+			r[i] == '-' && i+1 < len(r) && r[i+1] == '-' && (!mysql || i+2 == len(r) || unicode.IsSpace(r[i+2]) || unicode.IsControl(r[i+2])):
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case r[i] == '/' && i+1 < len(r) && r[i+1] == '*':
+			start := i
+			//содержимое /*! ... */ и /*M! ... */ MySQL и MariaDB выполняют как часть запроса
+			if mysql && i+2 < len(r) && (r[i+2] == '!' || r[i+2] == 'M' && i+3 < len(r) && r[i+3] == '!') {
+				return nil, &ParseError{Pos: start + 1, Message: "исполняемый комментарий", Unsupported: true}
+			}
+			for i += 2; i+1 < len(r) && !(r[i] == '*' && r[i+1] == '/'); i++ {
+			}
+			if i+1 >= len(r) {
+				return nil, &ParseError{Pos: start + 1, Message: "незакрытый комментарий"}
+			}
+			i += 2
+		case mysql && (r[i] == '\'' || r[i] == '"' || r[i] == '`'):
+			start, quote := i, r[i]
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(r) {
+					return nil, &ParseError{Pos: start + 1, Message: "незакрытая кавычка"}
+				}
+				if quote != '`' && r[i] == '\\' && i+1 < len(r) {
+					i++
+					b.WriteRune(r[i])
+					continue
+				}
+				if r[i] == quote {
+					if i+1 < len(r) && r[i+1] == quote {
+						b.WriteRune(quote)
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteRune(r[i])
+			}
+			kind := tokenString
+			if quote == '`' {
+				kind = tokenQuotedIdent
+			}
+			tokens = append(tokens, token{kind: kind, value: b.String(), pos: start + 1})
+		case r[i] == '\'' || r[i] == '"' || ((r[i] == 'e' || r[i] == 'E') && i+1 < len(r) && r[i+1] == '\''):
+			start, escapes := i, r[i] == 'e' || r[i] == 'E'
+			if escapes {
+				i++
+			}
+			quote := r[i]
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(r) {
+					return nil, &ParseError{Pos: start + 1, Message: "незакрытая кавычка"}
+				}
+				if escapes && r[i] == '\\' && i+1 < len(r) {
+					i++
+					b.WriteRune(r[i])
+					continue
+				}
+				if r[i] == quote {
+					if i+1 < len(r) && r[i+1] == quote {
+						b.WriteRune(quote)
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteRune(r[i])
+			}
+			kind := tokenString
+			if quote == '"' {
+				kind = tokenQuotedIdent
+			}
+			tokens = append(tokens, token{kind: kind, value: b.String(), pos: start + 1})
+		case !mysql && r[i] == '$' && i+1 < len(r) && unicode.IsDigit(r[i+1]):
+			start := i
+			for i++; i < len(r) && unicode.IsDigit(r[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenParam, value: string(r[start:i]), pos: start + 1})
+		case !mysql && r[i] == '$':
+			//строка в долларовых кавычках: $тег$...$тег$
+			start, end := i, indexRunes(r[i+1:], []rune("$"))
+			if end < 0 {
+				return nil, &ParseError{Pos: start + 1, Message: fmt.Sprintf("неизвестный символ %q", r[i])}
+			}
+			tag := r[i : i+end+2]
+			body := indexRunes(r[i+len(tag):], tag)
+			if body < 0 {
+				return nil, &ParseError{Pos: start + 1, Message: "незакрытая строка " + string(tag)}
+			}
+			i += len(tag)
+			tokens = append(tokens, token{kind: tokenString, value: string(r[i : i+body]), pos: start + 1})
+			i += body + len(tag)
+		case unicode.IsDigit(r[i]) || (r[i] == '.' && i+1 < len(r) && unicode.IsDigit(r[i+1])):
+			start := i
+			for i < len(r) && (unicode.IsDigit(r[i]) || r[i] == '.' || r[i] == 'e' || r[i] == 'E' ||
+				((r[i] == '+' || r[i] == '-') && (r[i-1] == 'e' || r[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(r[start:i]), pos: start + 1})
+		case unicode.IsLetter(r[i]) || r[i] == '_' || mysql && r[i] == '$':
+			start := i
+			for i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i]) || r[i] == '_' || r[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: string(r[start:i]), pos: start + 1})
+		default:
+			candidates := symbols[:]
+			if mysql {
+				candidates = append(mysqlSymbols[:], candidates...)
+			}
+
+			matched := false
+			for _, s := range candidates {
+				if strings.HasPrefix(string(r[i:]), s) {
+					tokens = append(tokens, token{kind: tokenSymbol, value: s, pos: i + 1})
+					i += len([]rune(s))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &ParseError{Pos: i + 1, Message: fmt.Sprintf("неизвестный символ %q", r[i])}
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(r) + 1}), nil
+}
+
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,1025 @@
+package querymodel
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parse переводит SELECT на PostgreSQL в модель запроса. Поддерживается подмножество:
+// колонки и функции от одной колонки, TABLESAMPLE корневой таблицы, JOIN/LEFT JOIN/RIGHT JOIN с условиями через AND,
+// WHERE с равенствами и IN через AND, GROUP BY по неагрегированным колонкам, ORDER BY, LIMIT и OFFSET.
+// Таблицы и колонки проверяются по tableList, функции - по functionList.
+func Parse(sql string, tableList []*dbmodel.Table, functionList []*dbmodel.Function) (Info, error) {
+	tokens, err := lex(sql)
+	if err != nil {
+		return Info{}, err
+	}
+
+	p := &parser{
+		tokens:  tokens,
+		tables:  tableIndex(tableList),
+		aliases: make(map[string]*Column),
+		calls:   make(map[*Column]*expr),
+	}
+
+	var info Info
+	if info, err = p.parse(); err != nil {
+		return Info{}, err
+	}
+
+	if err = info.Resolve(tableList, functionList); err != nil {
+		return Info{}, p.resolveError(err)
+	}
+
+	if err = p.checkCalls(); err != nil {
+		return Info{}, err
+	}
+
+	if err = p.checkGroupBy(info); err != nil {
+		return Info{}, err
+	}
+
+	return info, nil
+}
+
+type source struct {
+	key   TableKey
+	alias string
+	table *dbmodel.Table
+}
+
+// expr - колонка или функция от колонки до сопоставления с таблицами из FROM.
+type expr struct {
+	pos        int
+	qualifier  string
+	name       string
+	star       bool
+	function   string
+	args       []any
+	argIndex   int
+	orderedSet bool
+}
+
+type parser struct {
+	tokens []token
+	i      int
+
+	tables  map[TableKey]*dbmodel.Table
+	sources []*source
+	aliases map[string]*Column //псевдонимы колонок из SELECT
+
+	groupBy    []*Column
+	groupByPos int
+	samplePos  int
+
+	calls map[*Column]*expr //вызовы функций для сверки с видом функции после Resolve
+}
+
+var reserved = map[string]struct{}{
+	"select": {}, "from": {}, "where": {}, "group": {}, "having": {}, "order": {}, "limit": {}, "offset": {},
+	"join": {}, "inner": {}, "left": {}, "right": {}, "full": {}, "cross": {}, "natural": {}, "on": {},
+	"using": {}, "union": {}, "except": {}, "intersect": {}, "fetch": {}, "for": {}, "window": {}, "as": {},
+	"and": {}, "or": {}, "not": {}, "in": {}, "is": {}, "asc": {}, "desc": {}, "nulls": {}, "collate": {},
+	"within": {}, "filter": {}, "over": {}, "distinct": {}, "all": {}, "by": {}, "lateral": {},
+	"tablesample": {},
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) accept(s ...string) bool {
+	for j, w := range s {
+		if p.i+j >= len(p.tokens) || !p.tokens[p.i+j].is(w) {
+			return false
+		}
+	}
+	p.i += len(s)
+	return true
+}
+
+func (p *parser) expect(s string) error {
+	if t := p.peek(); !t.is(s) {
+		return p.errorf(t, "ожидалось %s, получено %s", strings.ToUpper(s), t)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, a ...any) error {
+	return &ParseError{Pos: t.pos, Message: fmt.Sprintf(format, a...)}
+}
+
+func (p *parser) unsupported(t token, construct string) error {
+	return &ParseError{Pos: t.pos, Message: construct, Unsupported: true}
+}
+
+func (p *parser) parse() (Info, error) {
+	info := Info{Type: Select}
+
+	if t := p.peek(); t.is("with") {
+		return Info{}, p.unsupported(t, "WITH")
+	}
+
+	if err := p.expect("select"); err != nil {
+		return Info{}, err
+	}
+
+	if t := p.peek(); t.is("distinct") {
+		return Info{}, p.unsupported(t, "DISTINCT")
+	}
+	p.accept("all")
+
+	var (
+		selectList []*expr
+		aliasList  []string
+	)
+
+	for {
+		e, err := p.parseExpr(true)
+		if err != nil {
+			return Info{}, err
+		}
+
+		var alias string
+		if alias, err = p.parseAlias(); err != nil {
+			return Info{}, err
+		}
+
+		selectList, aliasList = append(selectList, e), append(aliasList, alias)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.parseFrom(&info); err != nil {
+		return Info{}, err
+	}
+
+	for i, e := range selectList {
+		columns, err := p.resolve(e)
+		if err != nil {
+			return Info{}, err
+		}
+
+		if len(aliasList[i]) != 0 {
+			p.aliases[aliasList[i]] = columns[0]
+		}
+
+		info.Columns = append(info.Columns, columns...)
+	}
+
+	if err := p.parseClauses(&info); err != nil {
+		return Info{}, err
+	}
+
+	return info, nil
+}
+
+func (p *parser) parseAlias() (string, error) {
+	explicit := p.accept("as")
+
+	t := p.peek()
+	if t.kind == tokenQuotedIdent {
+		p.next()
+		return t.value, nil
+	}
+
+	if t.kind == tokenIdent {
+		if _, ok := reserved[strings.ToLower(t.value)]; !ok {
+			p.next()
+			return strings.ToLower(t.value), nil
+		}
+	}
+
+	if explicit {
+		return "", p.errorf(t, "ожидался псевдоним, получено %s", t)
+	}
+
+	return "", nil
+}
+
+func (p *parser) parseFrom(info *Info) error {
+	if err := p.expect("from"); err != nil {
+		return err
+	}
+
+	root, err := p.parseTable()
+	if err != nil {
+		return err
+	}
+	info.Table = &Table{TableKey: root.key}
+
+	if p.accept("tablesample") {
+		p.samplePos = p.peek().pos
+		if info.Sample, err = p.parseSample(); err != nil {
+			return err
+		}
+	}
+
+	if t := p.peek(); t.is(",") {
+		return p.unsupported(t, "перечисление таблиц через запятую")
+	}
+
+	for {
+		t := p.peek()
+
+		var joinType string
+		switch {
+		case p.accept("join"), p.accept("inner", "join"):
+			joinType = Join
+		case p.accept("left", "join"), p.accept("left", "outer", "join"):
+			joinType = Left
+		case p.accept("right", "join"), p.accept("right", "outer", "join"):
+			joinType = Right
+		case t.is("full"), t.is("cross"), t.is("natural"):
+			return p.unsupported(t, strings.ToUpper(t.value)+" JOIN")
+		case t.is(","):
+			return p.unsupported(t, "перечисление таблиц через запятую")
+		default:
+			return nil
+		}
+
+		s, err := p.parseTable()
+		if err != nil {
+			return err
+		}
+
+		if t = p.peek(); t.is("using") {
+			return p.unsupported(t, "JOIN ... USING")
+		} else if t.is("tablesample") {
+			return p.unsupported(t, "TABLESAMPLE для присоединённых таблиц")
+		}
+
+		if err = p.expect("on"); err != nil {
+			return err
+		}
+
+		rule := &Rule{Type: joinType}
+		for {
+			var c *Condition
+			if c, err = p.parseCondition(); err != nil {
+				return err
+			}
+			rule.Conditions = append(rule.Conditions, c)
+
+			if !p.accept("and") {
+				break
+			}
+		}
+
+		if t = p.peek(); t.is("or") {
+			return p.unsupported(t, "OR в условии соединения")
+		}
+
+		//соединения выполняются в порядке обхода дерева, поэтому все они подвешиваются к корню
+		info.Table.Next = append(info.Table.Next, &Table{TableKey: s.key, Rule: rule})
+	}
+}
+
+func (p *parser) parseTable() (*source, error) {
+	t := p.next()
+
+	switch {
+	case t.is("("):
+		return nil, p.unsupported(t, "подзапросы")
+	case t.is("lateral"):
+		return nil, p.unsupported(t, "LATERAL")
+	case t.kind != tokenIdent && t.kind != tokenQuotedIdent:
+		return nil, p.errorf(t, "ожидалось имя таблицы, получено %s", t)
+	}
+
+	key := TableKey{Name: identifier(t)}
+	if p.accept(".") {
+		n := p.next()
+		if n.kind != tokenIdent && n.kind != tokenQuotedIdent {
+			return nil, p.errorf(n, "ожидалось имя таблицы, получено %s", n)
+		}
+		key.Schema, key.Name = key.Name, identifier(n)
+	}
+
+	if n := p.peek(); n.is("(") {
+		return nil, p.unsupported(n, "табличные функции")
+	}
+
+	table, ok := p.tables[key]
+	if !ok {
+		return nil, p.errorf(t, "таблица %s не найдена", key)
+	}
+
+	alias, err := p.parseAlias()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &source{key: key, alias: alias, table: table}
+	for _, other := range p.sources {
+		if other.key.Schema == key.Schema && other.key.Name == key.Name {
+			s.key.Increment++
+		}
+		if len(alias) != 0 && other.alias == alias {
+			return nil, p.errorf(t, "псевдоним %s уже используется", alias)
+		}
+	}
+
+	p.sources = append(p.sources, s)
+	return s, nil
+}
+
+func (p *parser) parseSample() (*Sample, error) {
+	t := p.next()
+	if !t.is(SampleSystem) && !t.is(SampleBernoulli) {
+		return nil, p.unsupported(t, fmt.Sprintf("метод выборки %s", t))
+	}
+
+	sample := &Sample{Method: strings.ToUpper(t.value)}
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	n := p.next()
+	if n.kind != tokenNumber {
+		return nil, p.unsupported(n, fmt.Sprintf("выражение %s вместо процента выборки", n))
+	}
+
+	var err error
+	if sample.Percent, err = strconv.ParseFloat(n.value, 64); err != nil {
+		return nil, p.errorf(n, "ожидалось число, получено %s", n)
+	}
+
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if p.accept("repeatable") {
+		if err = p.expect("("); err != nil {
+			return nil, err
+		}
+
+		st := p.peek()
+
+		var seed any
+		if seed, err = p.parseLiteral(); err != nil {
+			return nil, err
+		}
+
+		i, ok := seed.(int64)
+		if !ok {
+			return nil, p.unsupported(st, "нецелый seed выборки")
+		}
+		sample.Seed = &i
+
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	return sample, nil
+}
+
+func (p *parser) parseCondition() (*Condition, error) {
+	left, err := p.parseColumnRef()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+
+	var operator string
+	switch {
+	case t.is("="):
+		operator = Equal
+	case t.is("!="), t.is("<>"):
+		operator = NotEqual
+	default:
+		return nil, p.unsupported(t, fmt.Sprintf("оператор %s в условии соединения", t))
+	}
+
+	var right *Column
+	if right, err = p.parseColumnRef(); err != nil {
+		return nil, err
+	}
+
+	return &Condition{Columns: [2]*Column{left, right}, Operator: operator}, nil
+}
+
+func (p *parser) parseColumnRef() (*Column, error) {
+	t := p.peek()
+
+	e, err := p.parseExpr(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e.function) != 0 {
+		return nil, p.unsupported(t, "функции в условиях")
+	}
+
+	var columns []*Column
+	if columns, err = p.resolve(e); err != nil {
+		return nil, err
+	}
+
+	return columns[0], nil
+}
+
+func (p *parser) parseClauses(info *Info) error {
+	if p.accept("where") {
+		if err := p.parseWhere(info); err != nil {
+			return err
+		}
+	}
+
+	//без GROUP BY ошибка группировки указывает на конец запроса
+	p.groupByPos = p.tokens[len(p.tokens)-1].pos
+	if t := p.peek(); p.accept("group", "by") {
+		p.groupByPos = t.pos
+		for {
+			e, err := p.parseExpr(false)
+			if err != nil {
+				return err
+			}
+
+			var columns []*Column
+			if columns, err = p.resolve(e); err != nil {
+				return err
+			}
+			for _, c := range columns {
+				//GROUP BY не попадает в модель и не проходит Resolve
+				delete(p.calls, c)
+			}
+			p.groupBy = append(p.groupBy, columns...)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if t := p.peek(); t.is("having") {
+		return p.unsupported(t, "HAVING")
+	}
+
+	if p.accept("order", "by") {
+		for {
+			c, err := p.parseOrderBy()
+			if err != nil {
+				return err
+			}
+			info.OrderBy = append(info.OrderBy, c)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("limit") {
+		if !p.accept("all") {
+			n, err := p.parseUint()
+			if err != nil {
+				return err
+			}
+			info.Limit = n
+		}
+	}
+
+	if p.accept("offset") {
+		n, err := p.parseUint()
+		if err != nil {
+			return err
+		}
+		info.Offset = n
+
+		if !p.accept("rows") {
+			p.accept("row")
+		}
+	}
+
+	t := p.peek()
+	switch {
+	case t.is("fetch"):
+		return p.unsupported(t, "FETCH")
+	case t.is("for"):
+		return p.unsupported(t, "FOR UPDATE/SHARE")
+	case t.is("union"), t.is("except"), t.is("intersect"):
+		return p.unsupported(t, strings.ToUpper(t.value))
+	case t.is("window"):
+		return p.unsupported(t, "WINDOW")
+	case t.is("limit"), t.is("offset"):
+		return p.unsupported(t, "LIMIT после OFFSET")
+	}
+
+	if p.accept(";") {
+		if t = p.peek(); t.kind != tokenEOF {
+			return p.unsupported(t, "несколько запросов")
+		}
+	}
+
+	if t = p.peek(); t.kind != tokenEOF {
+		return p.errorf(t, "неожиданный токен %s", t)
+	}
+
+	return nil
+}
+
+func (p *parser) parseWhere(info *Info) error {
+	seen := make(map[string]struct{})
+
+	for {
+		t := p.peek()
+		switch {
+		case t.is("("):
+			return p.unsupported(t, "скобки в WHERE")
+		case t.is("not"):
+			return p.unsupported(t, "NOT в WHERE")
+		case t.is("exists"):
+			return p.unsupported(t, "EXISTS")
+		}
+
+		c, err := p.parseColumnRef()
+		if err != nil {
+			return err
+		}
+
+		op := p.next()
+		switch {
+		case op.is("="):
+			if c.Value, err = p.parseLiteral(); err != nil {
+				return err
+			}
+			if c.Value == nil {
+				return p.unsupported(op, "сравнение с NULL")
+			}
+		case op.is("in"):
+			if n := p.peek(); n.is("(") && p.tokens[p.i+1].is("select") {
+				return p.unsupported(n, "подзапросы")
+			}
+			if err = p.expect("("); err != nil {
+				return err
+			}
+			var list []any
+			for {
+				var v any
+				if v, err = p.parseLiteral(); err != nil {
+					return err
+				}
+				list = append(list, v)
+
+				if !p.accept(",") {
+					break
+				}
+			}
+			if err = p.expect(")"); err != nil {
+				return err
+			}
+			c.Value = list
+		default:
+			return p.unsupported(op, fmt.Sprintf("оператор %s в WHERE", op))
+		}
+
+		key := c.StringWT(postgres)
+		if _, ok := seen[key]; ok {
+			return p.unsupported(t, "несколько условий для одной колонки")
+		}
+		seen[key] = struct{}{}
+
+		info.Where = append(info.Where, c)
+
+		if t = p.peek(); t.is("or") {
+			return p.unsupported(t, "OR в WHERE")
+		}
+
+		if !p.accept("and") {
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseOrderBy() (*Column, error) {
+	t := p.peek()
+	if t.kind == tokenNumber {
+		return nil, p.unsupported(t, "сортировка по номеру колонки")
+	}
+
+	var c *Column
+	if alias, ok := p.aliases[identifier(t)]; ok && (t.kind == tokenQuotedIdent || t.kind == tokenIdent) &&
+		!p.tokens[p.i+1].is(".") && !p.tokens[p.i+1].is("(") {
+		p.next()
+		column := *alias
+		column.ByAlias = true
+		c = &column
+	} else {
+		e, err := p.parseExpr(false)
+		if err != nil {
+			return nil, err
+		}
+
+		var columns []*Column
+		if columns, err = p.resolve(e); err != nil {
+			return nil, err
+		}
+		c = columns[0]
+	}
+
+	if p.accept("collate") {
+		n := p.next()
+		if n.kind != tokenIdent && n.kind != tokenQuotedIdent {
+			return nil, p.errorf(n, "ожидалось имя правила сортировки, получено %s", n)
+		}
+		c.Collate = n.value
+	}
+
+	if p.accept("desc") {
+		c.Desc = true
+	} else {
+		p.accept("asc")
+	}
+
+	if p.accept("nulls", "first") {
+		c.Nulls = NullsFirst
+	} else if p.accept("nulls", "last") {
+		c.Nulls = NullsLast
+	}
+
+	if t = p.peek(); t.is("using") {
+		return nil, p.unsupported(t, "ORDER BY ... USING")
+	}
+
+	return c, nil
+}
+
+func (p *parser) parseUint() (uint64, error) {
+	t := p.next()
+	if t.kind != tokenNumber {
+		return 0, p.errorf(t, "ожидалось число, получено %s", t)
+	}
+
+	n, err := strconv.ParseUint(t.value, 10, 64)
+	if err != nil {
+		return 0, p.errorf(t, "ожидалось целое неотрицательное число, получено %s", t)
+	}
+
+	return n, nil
+}
+
+func (p *parser) parseLiteral() (any, error) {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenString:
+		return t.value, nil
+	case t.kind == tokenNumber:
+		return number(t.value), nil
+	case t.is("-"):
+		n := p.next()
+		if n.kind != tokenNumber {
+			return nil, p.errorf(n, "ожидалось число, получено %s", n)
+		}
+		return number("-" + n.value), nil
+	case t.is("true"):
+		return true, nil
+	case t.is("false"):
+		return false, nil
+	case t.is("null"):
+		return nil, nil
+	case t.kind == tokenParam:
+		return nil, p.unsupported(t, "параметры запроса")
+	default:
+		return nil, p.unsupported(t, fmt.Sprintf("выражение %s вместо значения", t))
+	}
+}
+
+// parseExpr разбирает ссылку на колонку или вызов функции от одной колонки.
+func (p *parser) parseExpr(allowStar bool) (*expr, error) {
+	t := p.next()
+
+	if t.is("*") {
+		if !allowStar {
+			return nil, p.errorf(t, "неожиданный токен *")
+		}
+		return &expr{pos: t.pos, star: true}, nil
+	}
+
+	if t.kind != tokenIdent && t.kind != tokenQuotedIdent {
+		switch t.kind {
+		case tokenString, tokenNumber:
+			return nil, p.unsupported(t, "константы в списке колонок")
+		case tokenParam:
+			return nil, p.unsupported(t, "параметры запроса")
+		}
+		if t.is("(") {
+			return nil, p.unsupported(t, "подзапросы и выражения в скобках")
+		}
+		return nil, p.errorf(t, "ожидалась колонка, получено %s", t)
+	}
+
+	if t.kind == tokenIdent {
+		switch strings.ToLower(t.value) {
+		case "case", "cast", "exists", "array", "row", "not":
+			return nil, p.unsupported(t, strings.ToUpper(t.value))
+		}
+	}
+
+	var (
+		e   *expr
+		err error
+	)
+
+	if t.kind == tokenIdent && p.peek().is("(") {
+		if e, err = p.parseCall(t); err != nil {
+			return nil, err
+		}
+	} else {
+		e = &expr{pos: t.pos, name: identifier(t)}
+		if p.accept(".") {
+			n := p.next()
+			switch {
+			case n.is("*") && allowStar:
+				e.qualifier, e.name, e.star = e.name, "", true
+				return e, nil
+			case n.kind == tokenIdent || n.kind == tokenQuotedIdent:
+				e.qualifier, e.name = e.name, identifier(n)
+			default:
+				return nil, p.errorf(n, "ожидалось имя колонки, получено %s", n)
+			}
+
+			if p.peek().is(".") {
+				return nil, p.unsupported(t, "ссылки на колонки со схемой")
+			}
+		}
+	}
+
+	if n := p.peek(); n.is("::") {
+		return nil, p.unsupported(n, "приведение типов")
+	} else if n.kind == tokenSymbol && !n.is(",") && !n.is(")") && !n.is(";") &&
+		!n.is("=") && !n.is("!=") && !n.is("<>") && !n.is("<") && !n.is(">") && !n.is("<=") && !n.is(">=") {
+		return nil, p.unsupported(n, fmt.Sprintf("выражения с оператором %s", n))
+	} else if n.is("over") {
+		return nil, p.unsupported(n, "оконные функции")
+	} else if n.is("filter") {
+		return nil, p.unsupported(n, "FILTER")
+	}
+
+	return e, nil
+}
+
+func (p *parser) parseCall(name token) (*expr, error) {
+	e := &expr{pos: name.pos, function: strings.ToLower(name.value)}
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.is("*") {
+		return nil, p.unsupported(t, fmt.Sprintf("%s(*)", e.function))
+	} else if t.is("distinct") {
+		return nil, p.unsupported(t, "DISTINCT в агрегатных функциях")
+	}
+
+	hasColumn := false
+	for !p.peek().is(")") {
+		t := p.peek()
+		if t.kind == tokenIdent && !t.is("true") && !t.is("false") && !t.is("null") || t.kind == tokenQuotedIdent {
+			if hasColumn {
+				return nil, p.unsupported(t, "функции от нескольких колонок")
+			}
+
+			arg, err := p.parseExpr(false)
+			if err != nil {
+				return nil, err
+			}
+			if len(arg.function) != 0 {
+				return nil, p.unsupported(t, "вложенные функции")
+			}
+
+			e.qualifier, e.name, e.argIndex, hasColumn = arg.qualifier, arg.name, len(e.args), true
+		} else {
+			v, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			e.args = append(e.args, v)
+		}
+
+		if t = p.peek(); t.is("order") {
+			return nil, p.unsupported(t, "ORDER BY внутри агрегатной функции")
+		}
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); p.accept("within", "group") {
+		if hasColumn {
+			return nil, p.unsupported(t, "колонка среди прямых аргументов WITHIN GROUP")
+		}
+
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect("order"); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect("by"); err != nil {
+			return nil, err
+		}
+
+		arg, err := p.parseExpr(false)
+		if err != nil {
+			return nil, err
+		}
+		if len(arg.function) != 0 {
+			return nil, p.unsupported(t, "вложенные функции")
+		}
+
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		e.qualifier, e.name, e.argIndex, e.orderedSet, hasColumn = arg.qualifier, arg.name, len(e.args), true, true
+	}
+
+	if !hasColumn {
+		return nil, p.unsupported(name, "функции без колонки")
+	}
+
+	return e, nil
+}
+
+// resolve сопоставляет выражение с таблицами из FROM; звёздочка раскрывается в список колонок.
+func (p *parser) resolve(e *expr) ([]*Column, error) {
+	var candidates []*source
+
+	if len(e.qualifier) != 0 {
+		for _, s := range p.sources {
+			if s.alias == e.qualifier || (len(s.alias) == 0 && s.key.Name == e.qualifier) {
+				candidates = append(candidates, s)
+			}
+		}
+
+		if len(candidates) == 0 {
+			return nil, &ParseError{Pos: e.pos, Message: fmt.Sprintf("таблица %s не указана в FROM", e.qualifier)}
+		}
+
+		if len(candidates) > 1 {
+			return nil, &ParseError{Pos: e.pos, Message: fmt.Sprintf("ссылка на таблицу %s неоднозначна", e.qualifier)}
+		}
+	} else {
+		candidates = p.sources
+	}
+
+	if e.star {
+		var columns []*Column
+		for _, s := range candidates {
+			for _, c := range s.table.ColumnList {
+				column := &Column{TableKey: s.key}
+				column.Name = c.Name
+				columns = append(columns, column)
+			}
+		}
+		return columns, nil
+	}
+
+	var found *source
+	for _, s := range candidates {
+		for _, c := range s.table.ColumnList {
+			if c.Name != e.name {
+				continue
+			}
+
+			if found != nil {
+				return nil, &ParseError{Pos: e.pos, Message: fmt.Sprintf("колонка %s неоднозначна", e.name)}
+			}
+
+			found = s
+			break
+		}
+	}
+
+	if found == nil {
+		return nil, &ParseError{Pos: e.pos, Message: fmt.Sprintf("колонка %s не найдена", e.name)}
+	}
+
+	c := &Column{TableKey: found.key, Function: e.function, Args: e.args, ArgIndex: e.argIndex}
+	c.Name = e.name
+	if e.orderedSet {
+		c.FunctionKind = dbmodel.OrderedSet
+	}
+	if len(e.function) != 0 {
+		p.calls[c] = e
+	}
+
+	return []*Column{c}, nil
+}
+
+// resolveError указывает для ошибки Resolve позицию функции или выборки, к которой она относится,
+// иначе начало запроса.
+func (p *parser) resolveError(err error) error {
+	pe := &ParseError{Pos: 1, Message: err.Error()}
+
+	var ce *columnError
+	switch {
+	case errors.As(err, &ce):
+		if e, ok := p.calls[ce.column]; ok {
+			pe.Pos = e.pos
+		}
+	case p.samplePos != 0:
+		pe.Pos = p.samplePos
+	}
+
+	return pe
+}
+
+// checkCalls проверяет, что WITHIN GROUP указан именно для функций с сортировкой внутри группы.
+func (p *parser) checkCalls() error {
+	for c, e := range p.calls {
+		if c.FunctionKind == dbmodel.OrderedSet && !e.orderedSet {
+			return &ParseError{Pos: e.pos, Message: fmt.Sprintf("функция %s вызывается только с WITHIN GROUP", e.function)}
+		}
+		if c.FunctionKind != dbmodel.OrderedSet && e.orderedSet {
+			return &ParseError{Pos: e.pos, Message: fmt.Sprintf("функция %s не поддерживает WITHIN GROUP", e.function)}
+		}
+	}
+
+	return nil
+}
+
+// checkGroupBy проверяет, что GROUP BY совпадает с группировкой, которую построит buildSelect.
+func (p *parser) checkGroupBy(info Info) error {
+	var (
+		expected     []string
+		hasAggregate bool
+	)
+
+	for _, c := range info.Columns {
+		if len(c.Function) != 0 && c.FunctionKind != dbmodel.Scalar {
+			hasAggregate = true
+			continue
+		}
+		expected = append(expected, c.StringWT(postgres))
+	}
+
+	if !hasAggregate {
+		if len(p.groupBy) != 0 {
+			return &ParseError{Pos: p.groupByPos, Message: "GROUP BY без агрегатных функций", Unsupported: true}
+		}
+		return nil
+	}
+
+	given := make([]string, 0, len(p.groupBy))
+	for _, c := range p.groupBy {
+		given = append(given, c.StringWT(postgres))
+	}
+
+	sort.Strings(expected)
+	sort.Strings(given)
+
+	if strings.Join(expected, ",") != strings.Join(given, ",") {
+		return &ParseError{
+			Pos:         p.groupByPos,
+			Message:     "GROUP BY, который не совпадает со списком неагрегированных колонок",
+			Unsupported: true,
+		}
+	}
+
+	return nil
+}
+
+// identifier приводит имя без кавычек к нижнему регистру, как это делает PostgreSQL.
+func identifier(t token) string {
+	if t.kind == tokenQuotedIdent {
+		return t.value
+	}
+	return strings.ToLower(t.value)
+}
+
+func number(s string) any {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
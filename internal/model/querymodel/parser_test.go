@@ -0,0 +1,163 @@
+package querymodel
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tableList := []*dbmodel.Table{
+		{
+			Name: "example",
+			ColumnList: []*dbmodel.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "name", Type: "text"},
+				{Name: "age", Type: "integer"},
+			},
+		},
+		{
+			Name: "Order",
+			ColumnList: []*dbmodel.Column{
+				{Name: "example_id", Type: "integer"},
+				{Name: "total", Type: "numeric"},
+			},
+		},
+		{
+			Schema: "audit",
+			Name:   "example",
+			ColumnList: []*dbmodel.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "changed_by", Type: "text"},
+			},
+		},
+	}
+
+	functionList := []*dbmodel.Function{
+		{
+			Name:          "sum",
+			Kind:          dbmodel.Aggregate,
+			SignatureList: []*dbmodel.Signature{{ArgTypeList: []string{"integer"}}, {ArgTypeList: []string{"numeric"}}},
+		},
+		{
+			Name:          "lower",
+			Kind:          dbmodel.Scalar,
+			SignatureList: []*dbmodel.Signature{{ArgTypeList: []string{"text"}}},
+		},
+		{
+			Name:          "percentile_cont",
+			Kind:          dbmodel.OrderedSet,
+			SignatureList: []*dbmodel.Signature{{ArgTypeList: []string{"double precision", "double precision"}}},
+		},
+	}
+
+	tests := [...]struct {
+		sql           string
+		expectedQuery string
+		expectedArgs  []any
+	}{
+		{
+			sql: `select * from example where name = 'it''s' -- комментарий`,
+			expectedQuery: "SELECT \"example\".\"id\" \"example.id\", \"example\".\"name\" \"example.name\", " +
+				"\"example\".\"age\" \"example.age\" " +
+				"FROM \"example\" \"example\" " +
+				"WHERE \"example\".\"name\" = ?",
+			expectedArgs: []any{"it's"},
+		},
+		{
+			sql: `SELECT e.name, SUM(o.total) AS total
+				FROM example e
+				LEFT OUTER JOIN "Order" o ON o.example_id = e.id
+				JOIN example e2 ON e2.id = e.id AND e2.age <> e.age
+				WHERE e.age IN (18, 21)
+				GROUP BY e.name
+				ORDER BY total DESC NULLS LAST, e.name COLLATE "C"
+				LIMIT 10 OFFSET 20;`,
+			expectedQuery: "SELECT \"example\".\"name\" \"example.name\", " +
+				"sum(\"Order\".\"total\") \"sum(Order.total)\" " +
+				"FROM \"example\" \"example\" " +
+				"LEFT JOIN \"Order\" \"Order\" ON \"Order\".\"example_id\" = \"example\".\"id\" " +
+				"JOIN \"example\" \"example1\" ON \"example1\".\"id\" = \"example\".\"id\" " +
+				"AND \"example1\".\"age\" != \"example\".\"age\" " +
+				"WHERE \"example\".\"age\" IN (?,?) " +
+				"GROUP BY \"example\".\"name\" " +
+				"ORDER BY \"sum(Order.total)\" DESC NULLS LAST, \"example\".\"name\" COLLATE \"C\" " +
+				"LIMIT 10 OFFSET 20",
+			expectedArgs: []any{int64(18), int64(21)},
+		},
+		{
+			sql: `SELECT a.changed_by, lower(e.name) FROM audit.example a JOIN example e ON e.id = a.id`,
+			expectedQuery: "SELECT \"audit.example\".\"changed_by\" \"audit.example.changed_by\", " +
+				"lower(\"example\".\"name\") \"lower(example.name)\" " +
+				"FROM \"audit\".\"example\" \"audit.example\" " +
+				"JOIN \"example\" \"example\" ON \"example\".\"id\" = \"audit.example\".\"id\"",
+		},
+		{
+			sql: `SELECT lower(name), percentile_cont(0.5) WITHIN GROUP (ORDER BY age) FROM example GROUP BY lower(name)`,
+			expectedQuery: "SELECT lower(\"example\".\"name\") \"lower(example.name)\", " +
+				"percentile_cont(0.5) WITHIN GROUP (ORDER BY \"example\".\"age\") \"percentile_cont(example.age)\" " +
+				"FROM \"example\" \"example\" " +
+				"GROUP BY lower(\"example\".\"name\")",
+		},
+		{
+			sql: `SELECT name FROM example TABLESAMPLE bernoulli (2.5) REPEATABLE (42)`,
+			expectedQuery: "SELECT \"example\".\"name\" \"example.name\" " +
+				"FROM \"example\" \"example\" TABLESAMPLE BERNOULLI (2.5) REPEATABLE (42)",
+		},
+	}
+
+	for _, test := range tests {
+		info, err := Parse(test.sql, tableList, functionList)
+		if err != nil {
+			t.Errorf("%s --> произошла ошибка при разборе запроса: %s", test.sql, err)
+			continue
+		}
+
+		var query string
+		var args []any
+		if query, args, err = (Query{Info: info, b: b}).buildSelect().ToSql(); err != nil {
+			t.Errorf("%s --> произошла ошибка при построении запроса: %s", test.sql, err)
+		}
+
+		if query != test.expectedQuery || !reflect.DeepEqual(args, test.expectedArgs) {
+			t.Errorf(`query --> ожидалось: %s, получено: %s;
+args --> ожидалось: %v, получено: %v`, test.expectedQuery, query, test.expectedArgs, args)
+		}
+	}
+
+	errorTests := [...]struct {
+		sql         string
+		pos         int
+		unsupported bool
+	}{
+		{sql: "SELECT DISTINCT name FROM example", pos: 8, unsupported: true},
+		{sql: "SELECT name FROM example WHERE age = 1 OR age = 2", pos: 40, unsupported: true},
+		{sql: "SELECT count(*) FROM example", pos: 14, unsupported: true},
+		{sql: "SELECT name::int FROM example", pos: 12, unsupported: true},
+		{sql: "SELECT name FROM example; SELECT 1", pos: 27, unsupported: true},
+		{sql: "SELECT name, sum(age) FROM example", pos: 35, unsupported: true},
+		{sql: "SELECT name FROM missing", pos: 18},
+		{sql: "SELECT missing FROM example", pos: 8},
+		{sql: "SELECT id FROM example JOIN example e ON e.id = example.id", pos: 8},
+		{sql: "SELECT name FROM example WHERE name = 'x", pos: 39},
+		{sql: "SELECT id, lower(age) FROM example", pos: 12},
+		{sql: "SELECT id, upper(name) FROM example", pos: 12},
+		{sql: "SELECT id FROM example TABLESAMPLE SYSTEM (150)", pos: 36},
+	}
+
+	for _, test := range errorTests {
+		_, err := Parse(test.sql, tableList, functionList)
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%s --> ожидалась ошибка разбора, получено: %v", test.sql, err)
+			continue
+		}
+
+		if parseErr.Pos != test.pos || parseErr.Unsupported != test.unsupported {
+			t.Errorf("%s --> ожидалась позиция %d (не поддерживается: %t), получено: %s",
+				test.sql, test.pos, test.unsupported, parseErr)
+		}
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"datapoint/internal/model/dbmodel"
+	"errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// ErrInvalid - запрос не прошёл проверку по метаданным базы данных.
+var ErrInvalid = errors.New("запрос не прошёл проверку")
+
 const (
 	Select = "select"
 	Insert = "insert"
@@ -24,7 +30,8 @@ type Runner interface {
 
 type Query struct {
 	Info
-	b sq.StatementBuilderType //с PlaceholderFormat, но без RunWith
+	b       sq.StatementBuilderType //с PlaceholderFormat, но без RunWith
+	dialect dbmodel.Dialect         //nil - PostgreSQL
 }
 
 type Info struct {
@@ -35,9 +42,99 @@ type Info struct {
 	Where   []*Column
 	Limit   uint64
 	Offset  uint64
+	Sample  *Sample //выборка из корневой таблицы
+}
+
+// Redact возвращает копию запроса без значений колонок и дополнительных аргументов функций,
+// то есть без всего, что передаётся в базу данных параметрами. Исходный запрос не меняется.
+func (i Info) Redact() Info {
+	redact := func(list []*Column) []*Column {
+		if list == nil {
+			return nil
+		}
+		result := make([]*Column, 0, len(list))
+		for _, c := range list {
+			copied := *c
+			copied.Value, copied.Args = nil, nil
+			result = append(result, &copied)
+		}
+		return result
+	}
+
+	i.Columns, i.OrderBy, i.Where = redact(i.Columns), redact(i.OrderBy), redact(i.Where)
+	return i
+}
+
+const (
+	SampleSystem    = "SYSTEM"
+	SampleBernoulli = "BERNOULLI"
+)
+
+// Sample задаёт выборку либо процентом строк (TABLESAMPLE), либо их количеством.
+// Выборка по количеству читает всю таблицу и не поддерживает Seed.
+type Sample struct {
+	Method  string //SampleSystem или SampleBernoulli, только для Percent
+	Percent float64
+	Rows    uint64
+	Seed    *int64
+}
+
+func (s *Sample) check() error {
+	switch {
+	case s.Percent != 0 && s.Rows != 0:
+		return errors.New("выборка задаётся либо процентом, либо количеством строк")
+	case s.Rows != 0:
+		if len(s.Method) != 0 || s.Seed != nil {
+			return errors.New("метод и seed не поддерживаются для выборки по количеству строк")
+		}
+	case s.Percent <= 0 || s.Percent > 100 || math.IsNaN(s.Percent):
+		return fmt.Errorf("процент выборки должен быть больше 0 и не больше 100: %v", s.Percent)
+	case s.Method != SampleSystem && s.Method != SampleBernoulli:
+		return fmt.Errorf("неизвестный метод выборки: %s", s.Method)
+	}
+	return nil
+}
+
+// from возвращает корневую таблицу для FROM с учётом выборки.
+func (s *Sample) from(t *Table, d dbmodel.Dialect) string {
+	switch {
+	case s == nil:
+		return t.String(d)
+	case s.Rows != 0:
+		return fmt.Sprintf(`(SELECT * FROM %s ORDER BY %s LIMIT %d) %s`,
+			t.TableKey.Ref(d), d.Random(), s.Rows, d.Ident(t.TableKey.String()))
+	}
+
+	from := fmt.Sprintf(`%s TABLESAMPLE %s (%s)`, t.String(d), s.Method, strconv.FormatFloat(s.Percent, 'g', -1, 64))
+	if s.Seed != nil {
+		from += fmt.Sprintf(" REPEATABLE (%d)", *s.Seed)
+	}
+	return from
+}
+
+// postgres - диалект по умолчанию для запросов без базы данных и разбора SQL.
+var postgres, _ = dbmodel.DialectOf(dbmodel.PostgreSQL)
+
+func (q Query) Dialect() dbmodel.Dialect {
+	if q.dialect == nil {
+		return postgres
+	}
+	return q.dialect
+}
+
+// check проверяет то, что зависит от синтаксиса целевой базы данных.
+func (q Query) check() error {
+	if q.Type == Select && q.Sample != nil && q.Sample.Rows == 0 && !q.Dialect().TableSample() {
+		return errors.New("выборка процентом строк не поддерживается этой базой данных")
+	}
+	return nil
 }
 
 func (q Query) Execute(ctx context.Context, runner Runner) (QueryResult, error) {
+	if err := q.check(); err != nil {
+		return QueryResult{}, err
+	}
+
 	switch q.Type {
 	case Select:
 		return q.executeSelect(ctx, runner)
@@ -52,14 +149,35 @@ func (q Query) Execute(ctx context.Context, runner Runner) (QueryResult, error)
 	}
 }
 
+func (q Query) ToSql() (string, []any, error) {
+	if err := q.check(); err != nil {
+		return "", nil, err
+	}
+
+	switch q.Type {
+	case Select:
+		return q.buildSelect().ToSql()
+	case Insert:
+		return q.buildInsert().ToSql()
+	case Update:
+		return q.buildUpdate().ToSql()
+	case Delete:
+		return q.buildDelete().ToSql()
+	default:
+		return "", nil, fmt.Errorf("%s", q.Type)
+	}
+}
+
 func (q Query) buildSelect() sq.SelectBuilder {
+	d := q.Dialect()
+
 	b := q.b.
 		Select().
-		From(fmt.Sprintf(`"%s" "%s"`, q.Table.Name, q.Table))
+		From(q.Sample.from(q.Table, d))
 
 	next := q.Table.Next
 	for i := 0; i < len(next); i++ {
-		rule := fmt.Sprintf(`"%s" "%s" ON %s`, next[i].Name, next[i].TableKey, next[i].Rule.String())
+		rule := fmt.Sprintf(`%s ON %s`, next[i].String(d), next[i].Rule.String(d))
 		switch next[i].Rule.Type {
 		case Join:
 			b = b.Join(rule)
@@ -79,20 +197,16 @@ func (q Query) buildSelect() sq.SelectBuilder {
 	)
 
 	for _, c := range q.Columns {
-		b = b.Columns(c.StringWTWA())
-		if len(c.Function) != 0 {
+		b = b.Columns(c.StringWTWA(d))
+		if len(c.Function) != 0 && c.FunctionKind != dbmodel.Scalar {
 			hasFunction = true
 			continue
 		}
-		groupBy = append(groupBy, c.StringWT())
+		groupBy = append(groupBy, c.StringWT(d))
 	}
 
 	for _, c := range q.OrderBy {
-		var order string
-		if c.Desc {
-			order = " DESC"
-		}
-		b = b.OrderBy(c.StringWT() + order)
+		b = b.OrderBy(c.StringOrder(d))
 	}
 
 	var where sq.Eq
@@ -105,7 +219,7 @@ func (q Query) buildSelect() sq.SelectBuilder {
 			where = make(sq.Eq, len(q.Where))
 		}
 
-		where[c.StringWT()] = c.Value
+		where[c.StringWT(d)] = c.Value
 	}
 	if where != nil {
 		b = b.Where(where)
@@ -115,7 +229,7 @@ func (q Query) buildSelect() sq.SelectBuilder {
 		b = b.GroupBy(groupBy...)
 	}
 
-	return b
+	return d.Limit(b, q.Limit, q.Offset)
 }
 
 func (q Query) executeSelect(ctx context.Context, runner Runner) (QueryResult, error) {
@@ -130,16 +244,45 @@ func (q Query) executeSelect(ctx context.Context, runner Runner) (QueryResult, e
 	}
 	defer func() { _ = rows.Close() }()
 
+	var result QueryResult
+	if result, err = scan(rows, q.Dialect(), 0); err != nil {
+		return QueryResult{}, err
+	}
+
+	result.Sampled = q.Sample != nil
+	return result, nil
+}
+
+// binaryTypes - типы колонок после dbmodel.Dialect.NormalizeType, значения которых остаются []byte.
+var binaryTypes = map[string]struct{}{"bytea": {}, "bit": {}}
+
+// scan читает не больше limit строк (0 - без ограничения) вместе с типами колонок.
+func scan(rows *sql.Rows, d dbmodel.Dialect, limit int) (QueryResult, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return QueryResult{}, err
+	}
+
 	var (
-		data    []map[string]any
-		columns []string
+		result  QueryResult
+		columns = make([]string, 0, len(columnTypes))
+		text    = make(map[string]bool, len(columnTypes))
 	)
 
-	if columns, err = rows.Columns(); err != nil {
-		return QueryResult{}, err
+	for _, t := range columnTypes {
+		columns = append(columns, t.Name())
+		columnType := d.NormalizeType(t.DatabaseTypeName())
+		result.Columns = append(result.Columns, ResultColumn{Name: t.Name(), Type: columnType})
+		_, binary := binaryTypes[columnType]
+		text[t.Name()] = !binary
 	}
 
 	for rows.Next() {
+		if limit != 0 && len(result.Data) == limit {
+			result.Truncated = true
+			break
+		}
+
 		i, dest := make(map[string]any), make([]any, 0)
 
 		for _, c := range columns {
@@ -151,19 +294,32 @@ func (q Query) executeSelect(ctx context.Context, runner Runner) (QueryResult, e
 			return QueryResult{}, err
 		}
 
-		data = append(data, i)
+		//драйверы возвращают строки и числа произвольной точности как []byte, который JSON кодирует в base64
+		for c, v := range i {
+			if b, ok := (*v.(*any)).([]byte); ok && text[c] {
+				*v.(*any) = string(b)
+			}
+		}
+
+		result.Data = append(result.Data, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return QueryResult{}, err
 	}
 
-	return QueryResult{Data: data}, nil
+	result.RowCount = int64(len(result.Data))
+	return result, nil
 }
 
 func (q Query) buildInsert() sq.InsertBuilder {
-	b := q.b.Insert(strconv.Quote(q.Table.Name))
+	d := q.Dialect()
+	b := q.b.Insert(q.Table.TableKey.Ref(d))
 
 	values := make([]any, 0, len(q.Columns))
 
 	for _, c := range q.Columns {
-		b = b.Columns(strconv.Quote(c.Name))
+		b = b.Columns(d.Ident(c.Name))
 		values = append(values, c.Value)
 	}
 
@@ -176,22 +332,21 @@ func (q Query) executeInsert(ctx context.Context, runner Runner) (QueryResult, e
 		return QueryResult{}, err
 	}
 
-	_, err = runner.ExecContext(ctx, query, args...)
-
-	return QueryResult{}, err
+	return execute(ctx, runner, query, args)
 }
 
 func (q Query) buildUpdate() sq.UpdateBuilder {
-	b := q.b.Update(strconv.Quote(q.Table.Name))
+	d := q.Dialect()
+	b := q.b.Update(q.Table.TableKey.Ref(d))
 
 	for _, c := range q.Columns {
-		b = b.Set(strconv.Quote(c.Name), c.Value)
+		b = b.Set(d.Ident(c.Name), c.Value)
 	}
 
 	where := make(sq.Eq, len(q.Where))
 
 	for _, c := range q.Where {
-		where[strconv.Quote(c.Name)] = c.Value
+		where[d.Ident(c.Name)] = c.Value
 	}
 
 	return b.Where(where)
@@ -203,18 +358,17 @@ func (q Query) executeUpdate(ctx context.Context, runner Runner) (QueryResult, e
 		return QueryResult{}, err
 	}
 
-	_, err = runner.ExecContext(ctx, query, args...)
-
-	return QueryResult{}, err
+	return execute(ctx, runner, query, args)
 }
 
 func (q Query) buildDelete() sq.DeleteBuilder {
-	b := q.b.Delete(strconv.Quote(q.Table.Name))
+	d := q.Dialect()
+	b := q.b.Delete(q.Table.TableKey.Ref(d))
 
 	where := make(sq.Eq, len(q.Where))
 
 	for _, c := range q.Where {
-		where[strconv.Quote(c.Name)] = c.Value
+		where[d.Ident(c.Name)] = c.Value
 	}
 
 	return b.Where(where)
@@ -226,21 +380,46 @@ func (q Query) executeDelete(ctx context.Context, runner Runner) (QueryResult, e
 		return QueryResult{}, err
 	}
 
-	_, err = runner.ExecContext(ctx, query, args...)
+	return execute(ctx, runner, query, args)
+}
+
+func execute(ctx context.Context, runner Runner, query string, args []any) (QueryResult, error) {
+	result, err := runner.ExecContext(ctx, query, args...)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	var n int64
+	if n, err = result.RowsAffected(); err != nil {
+		return QueryResult{}, err
+	}
 
-	return QueryResult{}, err
+	return QueryResult{RowCount: n}, nil
 }
 
 type TableKey struct {
+	Schema    string //пустое значение - схема по умолчанию для базы данных
 	Name      string
 	Increment uint8
 }
 
 func (k TableKey) String() string {
+	name := k.Name
+	if len(k.Schema) != 0 {
+		name = k.Schema + "." + name
+	}
 	if k.Increment != 0 {
-		return fmt.Sprintf("%s%d", k.Name, k.Increment)
+		return fmt.Sprintf("%s%d", name, k.Increment)
+	}
+	return name
+}
+
+// Ref возвращает ссылку на таблицу, со схемой, если она указана.
+func (k TableKey) Ref(s dbmodel.Dialect) string {
+	if len(k.Schema) != 0 {
+		return s.Ident(k.Schema) + "." + s.Ident(k.Name)
 	}
-	return k.Name
+	return s.Ident(k.Name)
 }
 
 /*
@@ -251,31 +430,112 @@ func (k TableKey) String() string {
 
 type Column struct {
 	dbmodel.Column
-	TableKey TableKey
-	Function string
-	Desc     bool
-	Value    any
+	TableKey     TableKey
+	Function     string
+	FunctionKind string //заполняется Resolve, пустое значение == dbmodel.Aggregate
+	Args         []any  //дополнительные аргументы функции
+	ArgIndex     int    //позиция колонки среди аргументов функции
+	Desc         bool
+	Nulls        string //NullsFirst, NullsLast или пустое значение для порядка по умолчанию
+	Collate      string
+	ByAlias      bool //сортировка по псевдониму колонки из SELECT
+	Value        any
 }
 
-func (c Column) String() string {
+const (
+	NullsFirst = dbmodel.NullsFirst
+	NullsLast  = dbmodel.NullsLast
+)
+
+func (c Column) String(s dbmodel.Dialect) string {
 	if len(c.Function) != 0 {
-		return fmt.Sprintf(`%s("%s")`, c.Function, c.Name)
+		return c.call(s.Ident(c.Name), s)
 	}
-	return strconv.Quote(c.Name)
+	return s.Ident(c.Name)
 }
 
-func (c Column) StringWT() string {
+func (c Column) StringWT(s dbmodel.Dialect) string {
+	column := s.Ident(c.TableKey.String()) + "." + s.Ident(c.Name)
 	if len(c.Function) != 0 {
-		return fmt.Sprintf(`%s("%s"."%s")`, c.Function, c.TableKey, c.Name)
+		return c.call(column, s)
+	}
+	return column
+}
+
+// argIndex возвращает позицию колонки среди аргументов функции.
+func (c Column) argIndex() int {
+	if c.FunctionKind == dbmodel.OrderedSet || c.ArgIndex > len(c.Args) {
+		return len(c.Args)
+	}
+	if c.ArgIndex < 0 {
+		return 0
 	}
-	return fmt.Sprintf(`"%s"."%s"`, c.TableKey, c.Name)
+	return c.ArgIndex
 }
 
-func (c Column) StringWTWA() string {
+func (c Column) call(column string, s dbmodel.Dialect) string {
+	args := make([]string, 0, len(c.Args)+1)
+	for _, a := range c.Args {
+		args = append(args, literal(a, s))
+	}
+
+	if c.FunctionKind == dbmodel.OrderedSet {
+		return fmt.Sprintf("%s(%s) WITHIN GROUP (ORDER BY %s)", c.Function, strings.Join(args, ", "), column)
+	}
+
+	i := c.argIndex()
+	args = append(args[:i], append([]string{column}, args[i:]...)...)
+
+	return fmt.Sprintf("%s(%s)", c.Function, strings.Join(args, ", "))
+}
+
+// literal встраивает аргумент функции в запрос: аргументы функций попадают также в GROUP BY и ORDER BY,
+// где squirrel не принимает параметры.
+func literal(v any, s dbmodel.Dialect) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return s.String(fmt.Sprint(v))
+}
+
+func (c Column) Alias(s dbmodel.Dialect) string {
 	if len(c.Function) != 0 {
-		return fmt.Sprintf(`%s "%s(%s.%s)"`, c.StringWT(), c.Function, c.TableKey, c.Name)
+		return s.Ident(fmt.Sprintf("%s(%s.%s)", c.Function, c.TableKey, c.Name))
 	}
-	return fmt.Sprintf(`%s "%s.%s"`, c.StringWT(), c.TableKey, c.Name)
+	return s.Ident(fmt.Sprintf("%s.%s", c.TableKey, c.Name))
+}
+
+func (c Column) StringWTWA(s dbmodel.Dialect) string {
+	return c.StringWT(s) + " " + c.Alias(s)
+}
+
+// StringOrder возвращает выражение для ORDER BY.
+// Псевдоним нельзя использовать вместе с COLLATE, поэтому в этом случае сортировка идёт по выражению.
+func (c Column) StringOrder(s dbmodel.Dialect) string {
+	order := c.StringWT(s)
+	if c.ByAlias && len(c.Collate) == 0 {
+		order = c.Alias(s)
+	}
+
+	if len(c.Collate) != 0 {
+		order += " COLLATE " + s.Ident(c.Collate)
+	}
+
+	return s.Order(order, c.Desc, c.Nulls)
 }
 
 type Table struct {
@@ -284,6 +544,11 @@ type Table struct {
 	Rule *Rule
 }
 
+// String возвращает таблицу с псевдонимом для FROM и JOIN.
+func (t Table) String(s dbmodel.Dialect) string {
+	return t.TableKey.Ref(s) + " " + s.Ident(t.TableKey.String())
+}
+
 const (
 	Left  = "left"
 	Right = "right"
@@ -295,11 +560,11 @@ type Rule struct {
 	Conditions []*Condition
 }
 
-func (r Rule) String() string {
+func (r Rule) String(s dbmodel.Dialect) string {
 	and := make([]string, 0, len(r.Conditions))
 
 	for _, c := range r.Conditions {
-		and = append(and, fmt.Sprintf("%s %s %s", c.Columns[0].StringWT(), c.Operator, c.Columns[1].StringWT()))
+		and = append(and, fmt.Sprintf("%s %s %s", c.Columns[0].StringWT(s), c.Operator, c.Columns[1].StringWT(s)))
 	}
 
 	return strings.Join(and, " AND ")
@@ -315,11 +580,32 @@ type Condition struct {
 	Operator string
 }
 
-type QueryResult struct{ Data []map[string]any }
+type Running struct {
+	ID        string
+	DBID      string
+	Info      Info
+	Query     string //произвольный SQL из консоли вместо Info
+	Redacted  bool   //значения скрыты, как в истории
+	StartedAt time.Time
+}
+
+type QueryResult struct {
+	Columns   []ResultColumn
+	Data      []map[string]any
+	RowCount  int64
+	Truncated bool //строк больше, чем ограничение, и лишние не прочитаны
+	Sampled   bool //результат получен по выборке из таблицы
+}
+
+type ResultColumn struct {
+	Name string
+	Type string
+}
 
-func New(info Info, b sq.StatementBuilderType) Query {
+func New(info Info, b sq.StatementBuilderType, dialect dbmodel.Dialect) Query {
 	return Query{
-		Info: info,
-		b:    b,
+		Info:    info,
+		b:       b,
+		dialect: dialect,
 	}
 }
@@ -20,6 +20,8 @@ var b = sq.
 var table = &Table{TableKey: TableKey{Name: "example"}}
 
 func TestBuildSelect(t *testing.T) {
+	mysql, _ := dbmodel.DialectOf(dbmodel.MySQL)
+
 	tests := [...]test{
 		{
 			query: Query{
@@ -135,6 +137,138 @@ func TestBuildSelect(t *testing.T) {
 				"GROUP BY \"example\".\"id\", \"example\".\"name\", \"example1\".\"id\" " +
 				"ORDER BY \"example1\".\"name\" DESC",
 		},
+		{
+			query: Query{
+				Info: Info{
+					Type:  Select,
+					Table: table,
+					Columns: []*Column{
+						{
+							TableKey:     table.TableKey,
+							Column:       dbmodel.Column{Name: "name"},
+							Function:     "lower",
+							FunctionKind: dbmodel.Scalar,
+						},
+						{
+							TableKey:     table.TableKey,
+							Column:       dbmodel.Column{Name: "name"},
+							Function:     "string_agg",
+							FunctionKind: dbmodel.Aggregate,
+							Args:         []any{"it's, "},
+						},
+						{
+							TableKey:     table.TableKey,
+							Column:       dbmodel.Column{Name: "age"},
+							Function:     "percentile_cont",
+							FunctionKind: dbmodel.OrderedSet,
+							Args:         []any{0.5},
+						},
+					},
+				},
+				b: b,
+			},
+			expectedQuery: "SELECT lower(\"example\".\"name\") \"lower(example.name)\", " +
+				"string_agg(\"example\".\"name\", 'it''s, ') \"string_agg(example.name)\", " +
+				"percentile_cont(0.5) WITHIN GROUP (ORDER BY \"example\".\"age\") \"percentile_cont(example.age)\" " +
+				"FROM \"example\" \"example\" " +
+				"GROUP BY lower(\"example\".\"name\")",
+		},
+		{
+			query: Query{
+				Info: Info{
+					Type:  Select,
+					Table: table,
+					Columns: []*Column{
+						{
+							TableKey: table.TableKey,
+							Column:   dbmodel.Column{Name: "name"},
+						},
+						{
+							TableKey: table.TableKey,
+							Column:   dbmodel.Column{Name: "age"},
+							Function: "sum",
+						},
+					},
+					OrderBy: []*Column{
+						{
+							TableKey: table.TableKey,
+							Column:   dbmodel.Column{Name: "age"},
+							Function: "sum",
+							ByAlias:  true,
+							Desc:     true,
+							Nulls:    NullsLast,
+						},
+						{
+							TableKey: table.TableKey,
+							Column:   dbmodel.Column{Name: "name"},
+							Collate:  "C",
+							Nulls:    NullsFirst,
+						},
+					},
+				},
+				b: b,
+			},
+			expectedQuery: "SELECT \"example\".\"name\" \"example.name\", " +
+				"sum(\"example\".\"age\") \"sum(example.age)\" " +
+				"FROM \"example\" \"example\" " +
+				"GROUP BY \"example\".\"name\" " +
+				"ORDER BY \"sum(example.age)\" DESC NULLS LAST, " +
+				"\"example\".\"name\" COLLATE \"C\" NULLS FIRST",
+		},
+		{
+			query: Query{
+				Info: Info{
+					Type:  Select,
+					Table: table,
+					Columns: []*Column{
+						{TableKey: table.TableKey, Column: dbmodel.Column{Name: "name"}},
+					},
+					Limit:  10,
+					Sample: &Sample{Rows: 1000},
+				},
+				b: b,
+			},
+			expectedQuery: "SELECT \"example\".\"name\" \"example.name\" " +
+				"FROM (SELECT * FROM \"example\" ORDER BY random() LIMIT 1000) \"example\" " +
+				"LIMIT 10",
+		},
+		{
+			query: Query{
+				Info: Info{
+					Type:  Select,
+					Table: table,
+					Columns: []*Column{
+						{TableKey: table.TableKey, Column: dbmodel.Column{Name: "name"}},
+						{
+							TableKey:     table.TableKey,
+							Column:       dbmodel.Column{Name: "age"},
+							Function:     "group_concat",
+							FunctionKind: dbmodel.Aggregate,
+						},
+						{
+							TableKey:     table.TableKey,
+							Column:       dbmodel.Column{Name: "name"},
+							Function:     "concat",
+							FunctionKind: dbmodel.Scalar,
+							Args:         []any{`C:\`},
+						},
+					},
+					OrderBy: []*Column{
+						{TableKey: table.TableKey, Column: dbmodel.Column{Name: "name"}, Desc: true, Nulls: NullsFirst},
+					},
+					Offset: 5,
+				},
+				b:       b,
+				dialect: mysql,
+			},
+			expectedQuery: "SELECT `example`.`name` `example.name`, " +
+				"group_concat(`example`.`age`) `group_concat(example.age)`, " +
+				"concat(`example`.`name`, 'C:\\\\') `concat(example.name)` " +
+				"FROM `example` `example` " +
+				"GROUP BY `example`.`name`, concat(`example`.`name`, 'C:\\\\') " +
+				"ORDER BY `example`.`name` IS NULL DESC, `example`.`name` DESC " +
+				"LIMIT 18446744073709551615 OFFSET 5",
+		},
 	}
 
 	for _, test := range tests {
@@ -294,3 +428,71 @@ args --> ожидалось: %v, получено: %v`, test.expectedQuery, quer
 		}
 	}
 }
+
+func TestResolve(t *testing.T) {
+	tableList := []*dbmodel.Table{{
+		Name: "example",
+		ColumnList: []*dbmodel.Column{
+			{Name: "name", Type: "character varying"},
+			{Name: "created_at", Type: "timestamp without time zone"},
+		},
+	}}
+
+	functionList := []*dbmodel.Function{
+		{
+			Name: "lower",
+			Kind: dbmodel.Scalar,
+			SignatureList: []*dbmodel.Signature{
+				{ArgTypeList: []string{"text"}, ReturnType: "text"},
+			},
+		},
+		{
+			Name: "date_part",
+			Kind: dbmodel.Scalar,
+			SignatureList: []*dbmodel.Signature{
+				{ArgTypeList: []string{"text", "timestamp with time zone"}, ReturnType: "double precision"},
+			},
+		},
+	}
+
+	tests := [...]struct {
+		column      Column
+		expectedErr bool
+	}{
+		{column: Column{Column: dbmodel.Column{Name: "name"}, Function: "lower"}},
+		{column: Column{Column: dbmodel.Column{Name: "created_at"}, Function: "lower"}, expectedErr: true},
+		{column: Column{Column: dbmodel.Column{Name: "name"}, Function: "upper"}, expectedErr: true},
+		{column: Column{Column: dbmodel.Column{Name: "created_at"}, Function: "date_part", Args: []any{"year"}, ArgIndex: 1}},
+		{column: Column{Column: dbmodel.Column{Name: "created_at"}, Function: "date_part", Args: []any{"year"}}, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		c := test.column
+		c.TableKey = table.TableKey
+
+		info := Info{Type: Select, Table: table, Columns: []*Column{&c}}
+
+		err := info.Resolve(tableList, functionList)
+		if (err != nil) != test.expectedErr {
+			t.Errorf("%s(%s) --> ожидалась ошибка: %t, получено: %v", c.Function, c.Name, test.expectedErr, err)
+		}
+
+		if err == nil && c.FunctionKind != dbmodel.Scalar {
+			t.Errorf("%s(%s) --> ожидался вид функции: %s, получено: %s", c.Function, c.Name, dbmodel.Scalar, c.FunctionKind)
+		}
+	}
+
+	tableList = append(tableList, &dbmodel.Table{Name: "example_view", Kind: dbmodel.KindView})
+	view := &Table{TableKey: TableKey{Name: "example_view"}}
+
+	for _, queryType := range [...]string{Insert, Update, Delete} {
+		info := Info{Type: queryType, Table: view}
+		if err := info.Resolve(tableList, functionList); err == nil {
+			t.Errorf("%s example_view --> ожидалась ошибка изменения представления", queryType)
+		}
+	}
+
+	if err := (&Info{Type: Select, Table: view}).Resolve(tableList, functionList); err != nil {
+		t.Errorf("select example_view --> ошибка: %s", err)
+	}
+}
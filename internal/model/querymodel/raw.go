@@ -0,0 +1,123 @@
+package querymodel
+
+import (
+	"context"
+	"datapoint/internal/model/dbmodel"
+	"strings"
+)
+
+// Statement - произвольный SQL из консоли, проверенный NewStatement.
+type Statement struct {
+	SQL         string
+	returnsRows bool
+	mysql       bool
+}
+
+// forbidden - операторы, которые управляют транзакцией или параметрами сеанса
+// и позволили бы обойти ограничения консоли.
+var forbidden = map[string]struct{}{
+	"begin": {}, "start": {}, "commit": {}, "end": {}, "rollback": {}, "abort": {},
+	"savepoint": {}, "release": {}, "prepare": {}, "set": {}, "reset": {}, "discard": {},
+	"pragma": {}, "attach": {}, "detach": {},
+}
+
+// mysqlForbidden - операторы MySQL, которые дополнительно меняют состояние сеанса.
+var mysqlForbidden = map[string]struct{}{
+	"use": {}, "lock": {}, "unlock": {}, "xa": {}, "handler": {},
+}
+
+// rowStatements - операторы, которые возвращают строки.
+var rowStatements = map[string]struct{}{
+	"select": {}, "with": {}, "values": {}, "table": {}, "show": {}, "explain": {},
+}
+
+// NewStatement проверяет, что sql содержит ровно один оператор, который можно выполнить в консоли;
+// SQL разбирается по правилам драйвера driver.
+func NewStatement(sql, driver string) (Statement, error) {
+	mysql := driver == dbmodel.MySQL
+
+	tokens, err := lexDialect(sql, mysql)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	//завершающие точки с запятой не считаются отдельными операторами
+	end := len(tokens) - 1
+	for end > 0 && tokens[end-1].is(";") {
+		end--
+	}
+
+	if end == 0 {
+		return Statement{}, &ParseError{Pos: 1, Message: "пустой запрос"}
+	}
+
+	for _, t := range tokens[:end] {
+		if t.is(";") {
+			return Statement{}, &ParseError{Pos: t.pos, Message: "несколько запросов", Unsupported: true}
+		}
+	}
+
+	first := tokens[0]
+	if first.kind != tokenIdent {
+		return Statement{}, &ParseError{Pos: first.pos, Message: "ожидался оператор, получено " + first.String()}
+	}
+
+	keyword := strings.ToLower(first.value)
+	_, denied := forbidden[keyword]
+	if _, ok := mysqlForbidden[keyword]; ok && mysql {
+		denied = true
+	}
+	if denied {
+		return Statement{}, &ParseError{Pos: first.pos, Message: strings.ToUpper(first.value), Unsupported: true}
+	}
+
+	_, returnsRows := rowStatements[keyword]
+	for _, t := range tokens[:end] {
+		if t.is("returning") {
+			returnsRows = true
+			break
+		}
+	}
+
+	return Statement{SQL: sql, returnsRows: returnsRows, mysql: mysql}, nil
+}
+
+// Redact возвращает оператор, в котором строки и числа заменены на ?, для истории со скрытыми значениями.
+func (s Statement) Redact() string {
+	tokens, _ := lexDialect(s.SQL, s.mysql) //оператор уже разобран в NewStatement
+
+	parts := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenEOF:
+		case tokenString, tokenNumber:
+			parts = append(parts, "?")
+		case tokenQuotedIdent:
+			if s.mysql {
+				parts = append(parts, "`"+strings.ReplaceAll(t.value, "`", "``")+"`")
+			} else {
+				parts = append(parts, t.String())
+			}
+		default:
+			parts = append(parts, t.String())
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Execute выполняет оператор и читает не больше rowLimit строк (0 - без ограничения);
+// типы колонок результата приводятся диалектом d.
+func (s Statement) Execute(ctx context.Context, runner Runner, d dbmodel.Dialect, rowLimit int) (QueryResult, error) {
+	if !s.returnsRows {
+		return execute(ctx, runner, s.SQL, nil)
+	}
+
+	rows, err := runner.QueryContext(ctx, s.SQL)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scan(rows, d, rowLimit)
+}
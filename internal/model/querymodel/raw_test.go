@@ -0,0 +1,67 @@
+package querymodel
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"testing"
+)
+
+func TestNewStatement(t *testing.T) {
+	tests := [...]struct {
+		sql                 string
+		driver              string
+		expectedErr         bool
+		expectedReturnsRows bool
+	}{
+		{sql: "SELECT 1;;", expectedReturnsRows: true},
+		{sql: "select ';' as s -- ;", expectedReturnsRows: true},
+		{sql: "SELECT $$a;b$$", expectedReturnsRows: true},
+		{sql: "INSERT INTO t VALUES (1) RETURNING id", expectedReturnsRows: true},
+		{sql: "UPDATE t SET a = 1"},
+		{sql: "SELECT 1; SELECT 2", expectedErr: true},
+		{sql: "  ; ", expectedErr: true},
+		{sql: "COMMIT", expectedErr: true},
+		{sql: "set statement_timeout = 0", expectedErr: true},
+		{sql: `SELECT 'a\'' ; DROP TABLE t; -- '`, expectedReturnsRows: true},
+		{sql: `SELECT 'a\'' ; DROP TABLE t; -- '`, driver: dbmodel.MySQL, expectedErr: true},
+		{sql: "SELECT 1 # ; DROP TABLE t", driver: dbmodel.MySQL, expectedReturnsRows: true},
+		{sql: "SELECT 1 --1; DROP TABLE t", driver: dbmodel.MySQL, expectedErr: true},
+		{sql: "SELECT `a;b` FROM t", driver: dbmodel.MySQL, expectedReturnsRows: true},
+		{sql: "SELECT 1 /*!50000 ; SET autocommit = 0 */", driver: dbmodel.MySQL, expectedErr: true},
+		{sql: "SELECT @@version", driver: dbmodel.MySQL, expectedReturnsRows: true},
+		{sql: "LOCK TABLES t READ", driver: dbmodel.MySQL, expectedErr: true},
+		{sql: "LOCK TABLE t", driver: dbmodel.PostgreSQL},
+	}
+
+	for _, test := range tests {
+		s, err := NewStatement(test.sql, test.driver)
+		if (err != nil) != test.expectedErr {
+			t.Errorf("%s --> ожидалась ошибка: %t, получено: %v", test.sql, test.expectedErr, err)
+			continue
+		}
+
+		if err == nil && s.returnsRows != test.expectedReturnsRows {
+			t.Errorf("%s --> ожидалось возвращение строк: %t", test.sql, test.expectedReturnsRows)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := [...]struct {
+		sql, driver, expected string
+	}{
+		{sql: "UPDATE t SET password = 'секрет' WHERE id = 42", expected: "UPDATE t SET password = ? WHERE id = ?"},
+		{sql: `SELECT "Name" FROM t WHERE note = $$a;b$$`, expected: `SELECT "Name" FROM t WHERE note = ?`},
+		{sql: "SELECT `Name` FROM t WHERE note = \"it\\'s\"", driver: dbmodel.MySQL, expected: "SELECT `Name` FROM t WHERE note = ?"},
+	}
+
+	for _, test := range tests {
+		s, err := NewStatement(test.sql, test.driver)
+		if err != nil {
+			t.Fatalf("%s --> произошла ошибка: %s", test.sql, err)
+		}
+
+		if redacted := s.Redact(); redacted != test.expected {
+			t.Errorf("%s --> ожидалось: %s, получено: %s", test.sql, test.expected, redacted)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package querymodel
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"fmt"
+)
+
+// Resolve заполняет типы колонок и виды функций по метаданным базы данных
+// и проверяет, что функции разрешены и принимают переданные аргументы.
+func (i *Info) Resolve(tableList []*dbmodel.Table, functionList []*dbmodel.Function) error {
+	tables := tableIndex(tableList)
+
+	functions := make(map[string]*dbmodel.Function, len(functionList))
+	for _, f := range functionList {
+		functions[f.Name] = f
+	}
+
+	if i.Sample != nil {
+		if err := i.Sample.check(); err != nil {
+			return err
+		}
+	}
+
+	if i.Type != Select && i.Table != nil {
+		if t, ok := tables[TableKey{Schema: i.Table.Schema, Name: i.Table.Name}]; ok && t.ReadOnly() {
+			return fmt.Errorf("представление %s доступно только для чтения", t.Name)
+		}
+	}
+
+	for _, c := range i.OrderBy {
+		if c.Nulls != "" && c.Nulls != NullsFirst && c.Nulls != NullsLast {
+			return fmt.Errorf("неизвестный порядок NULL: %s", c.Nulls)
+		}
+	}
+
+	for _, list := range [...][]*Column{i.Columns, i.OrderBy, i.Where} {
+		for _, c := range list {
+			key := TableKey{Schema: c.TableKey.Schema, Name: c.TableKey.Name}
+			if len(key.Name) == 0 && i.Table != nil {
+				key = TableKey{Schema: i.Table.Schema, Name: i.Table.Name}
+			}
+
+			if t, ok := tables[key]; ok {
+				for _, tc := range t.ColumnList {
+					if tc.Name == c.Name {
+						c.Type = tc.Type
+						break
+					}
+				}
+			}
+
+			if len(c.Function) == 0 {
+				continue
+			}
+
+			if err := c.resolveFunction(functions[c.Function]); err != nil {
+				return &columnError{column: c, err: err}
+			}
+		}
+	}
+
+	return nil
+}
+
+// columnError запоминает колонку, на которой Resolve остановился, чтобы Parse мог указать её позицию.
+type columnError struct {
+	column *Column
+	err    error
+}
+
+func (e *columnError) Error() string {
+	return e.err.Error()
+}
+
+func (e *columnError) Unwrap() error {
+	return e.err
+}
+
+// tableIndex индексирует таблицы по схеме и имени, а также только по имени:
+// таблица без схемы - первая с таким именем в порядке схем базы данных.
+func tableIndex(tableList []*dbmodel.Table) map[TableKey]*dbmodel.Table {
+	tables := make(map[TableKey]*dbmodel.Table, len(tableList))
+	for _, t := range tableList {
+		tables[TableKey{Schema: t.Schema, Name: t.Name}] = t
+		if _, ok := tables[TableKey{Name: t.Name}]; !ok {
+			tables[TableKey{Name: t.Name}] = t
+		}
+	}
+	return tables
+}
+
+func (c *Column) resolveFunction(f *dbmodel.Function) error {
+	if f == nil {
+		return fmt.Errorf("функция %s не разрешена", c.Function)
+	}
+
+	if len(c.Type) == 0 {
+		return fmt.Errorf("не удалось определить тип колонки %s", c.Name)
+	}
+
+	c.FunctionKind = f.Kind
+
+	arity, i := len(c.Args)+1, c.argIndex()
+	for _, s := range f.SignatureList {
+		if len(s.ArgTypeList) == arity && s.Accepts(i, c.Type) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("функция %s не принимает %d аргументов с колонкой %s типа %s на позиции %d",
+		c.Function, arity, c.Name, c.Type, i+1)
+}
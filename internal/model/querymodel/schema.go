@@ -0,0 +1,148 @@
+package querymodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var schema = func() map[string]any {
+	var s map[string]any
+	if err := json.Unmarshal(Schema, &s); err != nil {
+		panic(fmt.Sprintf("не удалось разобрать schema.json: %s", err))
+	}
+	return s
+}()
+
+// validateSchema проверяет документ текущей версии по schema.json.
+func validateSchema(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if errs := validate(schema, schema, v, "$"); len(errs) > 0 {
+		slices.Sort(errs)
+		return fmt.Errorf("документ не соответствует схеме: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// validate проверяет значение только по тем ключевым словам JSON Schema, которые есть в schema.json.
+func validate(root, schema map[string]any, v any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := root["$defs"].(map[string]any)[strings.TrimPrefix(ref, "#/$defs/")]
+		return validate(root, def.(map[string]any), v, path)
+	}
+
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	for _, sub := range list(schema["allOf"]) {
+		errs = append(errs, validate(root, sub.(map[string]any), v, path)...)
+	}
+
+	if oneOf := list(schema["oneOf"]); len(oneOf) > 0 {
+		matched := 0
+		for _, sub := range oneOf {
+			if len(validate(root, sub.(map[string]any), v, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("подходит вариантов oneOf: %d", matched)
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("ожидалось %v", c)
+	}
+
+	if enum := list(schema["enum"]); len(enum) > 0 && !slices.Contains(enum, v) {
+		fail("значение %v не входит в %v", v, enum)
+	}
+
+	if t, ok := schema["type"]; ok {
+		types := list(t)
+		if s, ok := t.(string); ok {
+			types = []any{s}
+		}
+		if !slices.ContainsFunc(types, func(t any) bool { return typeOf(v, t.(string)) }) {
+			fail("ожидался тип %v", t)
+			return errs
+		}
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if m, ok := schema["minimum"].(float64); ok && v < m {
+			fail("значение меньше %v", m)
+		}
+		if m, ok := schema["exclusiveMinimum"].(float64); ok && v <= m {
+			fail("значение не больше %v", m)
+		}
+		if m, ok := schema["maximum"].(float64); ok && v > m {
+			fail("значение больше %v", m)
+		}
+	case string:
+		if m, ok := schema["minLength"].(float64); ok && float64(len(v)) < m {
+			fail("строка короче %v", m)
+		}
+	case []any:
+		if m, ok := schema["minItems"].(float64); ok && float64(len(v)) < m {
+			fail("элементов меньше %v", m)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, validate(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range list(schema["required"]) {
+			if _, ok := v[name.(string)]; !ok {
+				fail("нет обязательного поля %s", name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, value := range v {
+			property, ok := properties[name]
+			if !ok {
+				if schema["additionalProperties"] == false {
+					fail("лишнее поле %s", name)
+				}
+				continue
+			}
+			errs = append(errs, validate(root, property.(map[string]any), value, path+"."+name)...)
+		}
+	}
+
+	return errs
+}
+
+func list(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func typeOf(v any, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || t == "integer" && v == float64(int64(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
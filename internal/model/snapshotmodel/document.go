@@ -0,0 +1,226 @@
+package snapshotmodel
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"encoding/json"
+)
+
+// DocTable - таблица в том виде, в котором она хранится в снимке и попадает в отпечаток.
+// Формат не зависит от dbmodel.Table: изменение модели не должно менять старые снимки и их отпечатки.
+type DocTable struct {
+	Schema     string           `json:"schema,omitempty"`
+	Name       string           `json:"name"`
+	Kind       string           `json:"kind"`
+	Definition string           `json:"definition,omitempty"`
+	Comment    string           `json:"comment,omitempty"`
+	ColumnList []*DocColumn     `json:"columnList"`
+	IndexList  []*DocIndex      `json:"indexList,omitempty"`
+	UniqueList []*DocConstraint `json:"uniqueList,omitempty"`
+	CheckList  []*DocConstraint `json:"checkList,omitempty"`
+	FKList     []*DocForeignKey `json:"fkList,omitempty"`
+}
+
+type DocColumn struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	IsRequired  bool     `json:"isRequired,omitempty"`
+	IsPK        bool     `json:"isPK,omitempty"`
+	FK          *DocFK   `json:"fk,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	IsIdentity  bool     `json:"isIdentity,omitempty"`
+	IsSerial    bool     `json:"isSerial,omitempty"`
+	IsGenerated bool     `json:"isGenerated,omitempty"`
+	Generated   string   `json:"generated,omitempty"`
+	MaxLength   *int     `json:"maxLength,omitempty"`
+	Precision   *int     `json:"precision,omitempty"`
+	Scale       *int     `json:"scale,omitempty"`
+	ElementType string   `json:"elementType,omitempty"`
+	EnumList    []string `json:"enumList,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
+}
+
+type DocFK struct {
+	Schema     string `json:"schema,omitempty"`
+	TableName  string `json:"tableName"`
+	ColumnName string `json:"columnName"`
+}
+
+type DocIndex struct {
+	Name       string   `json:"name"`
+	ColumnList []string `json:"columnList"`
+	IsUnique   bool     `json:"isUnique,omitempty"`
+	Method     string   `json:"method,omitempty"`
+	Predicate  string   `json:"predicate,omitempty"`
+}
+
+type DocConstraint struct {
+	Name       string   `json:"name"`
+	ColumnList []string `json:"columnList,omitempty"`
+	Expression string   `json:"expression,omitempty"`
+}
+
+type DocForeignKey struct {
+	Name          string   `json:"name,omitempty"`
+	ColumnList    []string `json:"columnList"`
+	RefSchema     string   `json:"refSchema,omitempty"`
+	RefTable      string   `json:"refTable"`
+	RefColumnList []string `json:"refColumnList"`
+	OnDelete      string   `json:"onDelete"`
+	OnUpdate      string   `json:"onUpdate"`
+}
+
+// Encode переводит список таблиц в формат снимка.
+func Encode(tableList []*dbmodel.Table) ([]byte, error) {
+	list := make([]*DocTable, 0, len(tableList))
+	for _, t := range tableList {
+		list = append(list, newDocTable(t))
+	}
+	return json.Marshal(list)
+}
+
+// Decode читает список таблиц из формата снимка.
+func Decode(data []byte) ([]*dbmodel.Table, error) {
+	var list []*DocTable
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	tableList := make([]*dbmodel.Table, 0, len(list))
+	for _, t := range list {
+		tableList = append(tableList, t.table())
+	}
+	return tableList, nil
+}
+
+func newDocTable(t *dbmodel.Table) *DocTable {
+	dt := &DocTable{
+		Schema:     t.Schema,
+		Name:       t.Name,
+		Kind:       t.Kind,
+		Definition: t.Definition,
+		Comment:    t.Comment,
+		ColumnList: make([]*DocColumn, 0, len(t.ColumnList)),
+	}
+
+	for _, c := range t.ColumnList {
+		dc := &DocColumn{
+			Name:        c.Name,
+			Type:        c.Type,
+			IsRequired:  c.IsRequired,
+			IsPK:        c.IsPK,
+			Default:     c.Default,
+			IsIdentity:  c.IsIdentity,
+			IsSerial:    c.IsSerial,
+			IsGenerated: c.IsGenerated,
+			Generated:   c.Generated,
+			MaxLength:   c.MaxLength,
+			Precision:   c.Precision,
+			Scale:       c.Scale,
+			ElementType: c.ElementType,
+			EnumList:    c.EnumList,
+			Comment:     c.Comment,
+		}
+		if c.FK != nil {
+			dc.FK = &DocFK{Schema: c.FK.Schema, TableName: c.FK.TableName, ColumnName: c.FK.ColumnName}
+		}
+		dt.ColumnList = append(dt.ColumnList, dc)
+	}
+
+	for _, i := range t.IndexList {
+		dt.IndexList = append(dt.IndexList, &DocIndex{
+			Name:       i.Name,
+			ColumnList: i.ColumnList,
+			IsUnique:   i.IsUnique,
+			Method:     i.Method,
+			Predicate:  i.Predicate,
+		})
+	}
+
+	for _, c := range t.UniqueList {
+		dt.UniqueList = append(dt.UniqueList, &DocConstraint{Name: c.Name, ColumnList: c.ColumnList, Expression: c.Expression})
+	}
+
+	for _, c := range t.CheckList {
+		dt.CheckList = append(dt.CheckList, &DocConstraint{Name: c.Name, ColumnList: c.ColumnList, Expression: c.Expression})
+	}
+
+	for _, fk := range t.FKList {
+		dt.FKList = append(dt.FKList, &DocForeignKey{
+			Name:          fk.Name,
+			ColumnList:    fk.ColumnList,
+			RefSchema:     fk.RefSchema,
+			RefTable:      fk.RefTable,
+			RefColumnList: fk.RefColumnList,
+			OnDelete:      fk.OnDelete,
+			OnUpdate:      fk.OnUpdate,
+		})
+	}
+
+	return dt
+}
+
+func (dt *DocTable) table() *dbmodel.Table {
+	t := &dbmodel.Table{
+		Schema:     dt.Schema,
+		Name:       dt.Name,
+		Kind:       dt.Kind,
+		Definition: dt.Definition,
+		Comment:    dt.Comment,
+	}
+
+	for _, dc := range dt.ColumnList {
+		c := &dbmodel.Column{
+			Name:        dc.Name,
+			Type:        dc.Type,
+			IsRequired:  dc.IsRequired,
+			IsPK:        dc.IsPK,
+			Default:     dc.Default,
+			IsIdentity:  dc.IsIdentity,
+			IsSerial:    dc.IsSerial,
+			IsGenerated: dc.IsGenerated,
+			Generated:   dc.Generated,
+			MaxLength:   dc.MaxLength,
+			Precision:   dc.Precision,
+			Scale:       dc.Scale,
+			ElementType: dc.ElementType,
+			EnumList:    dc.EnumList,
+			Comment:     dc.Comment,
+		}
+		if dc.FK != nil {
+			c.FK = &dbmodel.FK{Schema: dc.FK.Schema, TableName: dc.FK.TableName, ColumnName: dc.FK.ColumnName}
+		}
+		t.ColumnList = append(t.ColumnList, c)
+	}
+
+	for _, di := range dt.IndexList {
+		t.IndexList = append(t.IndexList, &dbmodel.Index{
+			Name:       di.Name,
+			ColumnList: di.ColumnList,
+			IsUnique:   di.IsUnique,
+			Method:     di.Method,
+			Predicate:  di.Predicate,
+		})
+	}
+
+	for _, dc := range dt.UniqueList {
+		t.UniqueList = append(t.UniqueList, &dbmodel.Constraint{Name: dc.Name, ColumnList: dc.ColumnList, Expression: dc.Expression})
+	}
+
+	for _, dc := range dt.CheckList {
+		t.CheckList = append(t.CheckList, &dbmodel.Constraint{Name: dc.Name, ColumnList: dc.ColumnList, Expression: dc.Expression})
+	}
+
+	for _, dfk := range dt.FKList {
+		t.FKList = append(t.FKList, &dbmodel.ForeignKey{
+			Name:          dfk.Name,
+			ColumnList:    dfk.ColumnList,
+			RefSchema:     dfk.RefSchema,
+			RefTable:      dfk.RefTable,
+			RefColumnList: dfk.RefColumnList,
+			OnDelete:      dfk.OnDelete,
+			OnUpdate:      dfk.OnUpdate,
+		})
+	}
+
+	return t
+}
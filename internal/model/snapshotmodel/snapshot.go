@@ -0,0 +1,189 @@
+package snapshotmodel
+
+import (
+	"crypto/sha256"
+	"datapoint/internal/model/dbmodel"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("снимка схемы не существует")
+
+// Snapshot - сохранённая версия списка таблиц базы данных.
+type Snapshot struct {
+	ID        string
+	DBID      string
+	Version   int //нумерация своя у каждой базы данных, с 1
+	Hash      string
+	TableList []*dbmodel.Table //не заполняется в списке снимков
+	CreatedAt time.Time
+}
+
+// Hash - отпечаток списка таблиц в формате снимка; снимок не сохраняется, если схема не изменилась.
+func Hash(tableList []*dbmodel.Table) (string, error) {
+	data, err := Encode(tableList)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type Diff struct {
+	From          int
+	To            int
+	AddedTables   []string
+	RemovedTables []string
+	TableList     []*TableDiff //таблицы, которые есть в обоих снимках и изменились
+}
+
+type TableDiff struct {
+	Schema         string
+	Name           string
+	AddedColumns   []string
+	RemovedColumns []string
+	ColumnList     []*ColumnDiff
+	AddedFKs       []*dbmodel.ForeignKey
+	RemovedFKs     []*dbmodel.ForeignKey
+}
+
+// ColumnDiff - изменение типа колонки, вместе с длиной, точностью и типом элемента массива.
+type ColumnDiff struct {
+	Name     string
+	FromType string
+	ToType   string
+}
+
+// Compare сравнивает два снимка. Изменение действий внешнего ключа считается
+// удалением старого ключа и добавлением нового.
+func Compare(from, to *Snapshot) Diff {
+	d := Diff{From: from.Version, To: to.Version}
+
+	fromTables := make(map[string]*dbmodel.Table, len(from.TableList))
+	for _, t := range from.TableList {
+		fromTables[tableName(t)] = t
+	}
+
+	toTables := make(map[string]bool, len(to.TableList))
+	for _, t := range to.TableList {
+		name := tableName(t)
+		toTables[name] = true
+
+		old, ok := fromTables[name]
+		if !ok {
+			d.AddedTables = append(d.AddedTables, name)
+			continue
+		}
+
+		if td := compareTable(old, t); td != nil {
+			d.TableList = append(d.TableList, td)
+		}
+	}
+
+	for _, t := range from.TableList {
+		if name := tableName(t); !toTables[name] {
+			d.RemovedTables = append(d.RemovedTables, name)
+		}
+	}
+
+	return d
+}
+
+func compareTable(from, to *dbmodel.Table) *TableDiff {
+	td := &TableDiff{Schema: to.Schema, Name: to.Name}
+
+	fromColumns := make(map[string]*dbmodel.Column, len(from.ColumnList))
+	for _, c := range from.ColumnList {
+		fromColumns[c.Name] = c
+	}
+
+	toColumns := make(map[string]bool, len(to.ColumnList))
+	for _, c := range to.ColumnList {
+		toColumns[c.Name] = true
+
+		old, ok := fromColumns[c.Name]
+		if !ok {
+			td.AddedColumns = append(td.AddedColumns, c.Name)
+			continue
+		}
+
+		if fromType, toType := columnType(old), columnType(c); fromType != toType {
+			td.ColumnList = append(td.ColumnList, &ColumnDiff{Name: c.Name, FromType: fromType, ToType: toType})
+		}
+	}
+
+	for _, c := range from.ColumnList {
+		if !toColumns[c.Name] {
+			td.RemovedColumns = append(td.RemovedColumns, c.Name)
+		}
+	}
+
+	fromFKs := make(map[string]bool, len(from.FKList))
+	for _, fk := range from.FKList {
+		fromFKs[fkKey(fk)] = true
+	}
+
+	toFKs := make(map[string]bool, len(to.FKList))
+	for _, fk := range to.FKList {
+		key := fkKey(fk)
+		toFKs[key] = true
+		if !fromFKs[key] {
+			td.AddedFKs = append(td.AddedFKs, fk)
+		}
+	}
+
+	for _, fk := range from.FKList {
+		if !toFKs[fkKey(fk)] {
+			td.RemovedFKs = append(td.RemovedFKs, fk)
+		}
+	}
+
+	if len(td.AddedColumns) == 0 && len(td.RemovedColumns) == 0 && len(td.ColumnList) == 0 &&
+		len(td.AddedFKs) == 0 && len(td.RemovedFKs) == 0 {
+		return nil
+	}
+	return td
+}
+
+func tableName(t *dbmodel.Table) string {
+	if t.Schema == "" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// columnType описывает тип колонки одной строкой: character varying(50), numeric(10,2), integer[].
+func columnType(c *dbmodel.Column) string {
+	t := c.Type
+	if c.ElementType != "" {
+		t = c.ElementType + "[]"
+	}
+
+	switch {
+	case c.MaxLength != nil:
+		t += fmt.Sprintf("(%d)", *c.MaxLength)
+	case c.Precision != nil && c.Scale != nil:
+		t += fmt.Sprintf("(%d,%d)", *c.Precision, *c.Scale)
+	case c.Precision != nil:
+		t += fmt.Sprintf("(%d)", *c.Precision)
+	}
+
+	return t
+}
+
+// fkKey сравнивает внешние ключи по содержанию: в SQLite у них нет имён.
+func fkKey(fk *dbmodel.ForeignKey) string {
+	return strings.Join([]string{
+		fk.Name,
+		strings.Join(fk.ColumnList, ","),
+		fk.RefSchema,
+		fk.RefTable,
+		strings.Join(fk.RefColumnList, ","),
+		fk.OnDelete,
+		fk.OnUpdate,
+	}, "\x00")
+}
@@ -0,0 +1,167 @@
+package snapshotmodel_test
+
+import (
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/snapshotmodel"
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	length := func(n int) *int { return &n }
+
+	customer := func(nameLength int, columns ...*dbmodel.Column) *dbmodel.Table {
+		return &dbmodel.Table{
+			Schema: "public",
+			Name:   "customer",
+			ColumnList: append([]*dbmodel.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "name", Type: "character varying", MaxLength: length(nameLength)},
+			}, columns...),
+		}
+	}
+
+	orders := func(onDelete string) *dbmodel.Table {
+		return &dbmodel.Table{
+			Schema:     "public",
+			Name:       "orders",
+			ColumnList: []*dbmodel.Column{{Name: "id", Type: "integer"}, {Name: "customer_id", Type: "integer"}},
+			FKList: []*dbmodel.ForeignKey{{
+				Name:          "orders_customer_fk",
+				ColumnList:    []string{"customer_id"},
+				RefSchema:     "public",
+				RefTable:      "customer",
+				RefColumnList: []string{"id"},
+				OnDelete:      onDelete,
+				OnUpdate:      dbmodel.NoAction,
+			}},
+		}
+	}
+
+	note := &dbmodel.Column{Name: "note", Type: "text"}
+	legacy := &dbmodel.Table{Schema: "public", Name: "legacy", ColumnList: []*dbmodel.Column{{Name: "id", Type: "integer"}}}
+	audit := &dbmodel.Table{Schema: "audit", Name: "log", ColumnList: []*dbmodel.Column{{Name: "id", Type: "bigint"}}}
+
+	for _, test := range []struct {
+		name string
+		from []*dbmodel.Table
+		to   []*dbmodel.Table
+		diff snapshotmodel.Diff
+	}{
+		{
+			name: "без изменений",
+			from: []*dbmodel.Table{customer(100), orders(dbmodel.NoAction)},
+			to:   []*dbmodel.Table{customer(100), orders(dbmodel.NoAction)},
+			diff: snapshotmodel.Diff{From: 1, To: 2},
+		},
+		{
+			name: "добавленная и удалённая таблица",
+			from: []*dbmodel.Table{customer(100), legacy},
+			to:   []*dbmodel.Table{customer(100), audit},
+			diff: snapshotmodel.Diff{From: 1, To: 2, AddedTables: []string{"audit.log"}, RemovedTables: []string{"public.legacy"}},
+		},
+		{
+			name: "колонки и длина строки",
+			from: []*dbmodel.Table{customer(100, note)},
+			to:   []*dbmodel.Table{customer(200, &dbmodel.Column{Name: "email", Type: "text"})},
+			diff: snapshotmodel.Diff{From: 1, To: 2, TableList: []*snapshotmodel.TableDiff{{
+				Schema:         "public",
+				Name:           "customer",
+				AddedColumns:   []string{"email"},
+				RemovedColumns: []string{"note"},
+				ColumnList: []*snapshotmodel.ColumnDiff{
+					{Name: "name", FromType: "character varying(100)", ToType: "character varying(200)"},
+				},
+			}}},
+		},
+		{
+			name: "изменённое действие внешнего ключа",
+			from: []*dbmodel.Table{orders(dbmodel.NoAction)},
+			to:   []*dbmodel.Table{orders(dbmodel.Cascade)},
+			diff: snapshotmodel.Diff{From: 1, To: 2, TableList: []*snapshotmodel.TableDiff{{
+				Schema:     "public",
+				Name:       "orders",
+				AddedFKs:   orders(dbmodel.Cascade).FKList,
+				RemovedFKs: orders(dbmodel.NoAction).FKList,
+			}}},
+		},
+	} {
+		diff := snapshotmodel.Compare(
+			&snapshotmodel.Snapshot{Version: 1, TableList: test.from},
+			&snapshotmodel.Snapshot{Version: 2, TableList: test.to},
+		)
+		if !reflect.DeepEqual(diff, test.diff) {
+			t.Errorf("%s --> ожидалось: %+v, получено: %+v", test.name, test.diff, diff)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	table := func(columnType string) []*dbmodel.Table {
+		return []*dbmodel.Table{{Name: "t", ColumnList: []*dbmodel.Column{{Name: "id", Type: columnType}}}}
+	}
+
+	first, err := snapshotmodel.Hash(table("integer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if same, _ := snapshotmodel.Hash(table("integer")); same != first {
+		t.Errorf("одинаковые схемы --> ожидался одинаковый отпечаток")
+	}
+
+	if other, _ := snapshotmodel.Hash(table("bigint")); other == first {
+		t.Errorf("разные схемы --> ожидались разные отпечатки")
+	}
+}
+
+func TestDocument(t *testing.T) {
+	length := 100
+	tableList := []*dbmodel.Table{{
+		Schema:  "public",
+		Name:    "orders",
+		Kind:    dbmodel.KindTable,
+		Comment: "заказы",
+		ColumnList: []*dbmodel.Column{
+			{Name: "id", Type: "integer", IsRequired: true, IsPK: true, IsIdentity: true},
+			{Name: "customer_id", Type: "integer", FK: &dbmodel.FK{Schema: "public", TableName: "customer", ColumnName: "id"}},
+			{Name: "note", Type: "character varying", MaxLength: &length, Default: "''::character varying"},
+		},
+		IndexList:  []*dbmodel.Index{{Name: "orders_note_idx", ColumnList: []string{"note"}, Method: "btree", Predicate: "note IS NOT NULL"}},
+		UniqueList: []*dbmodel.Constraint{{Name: "orders_note_key", ColumnList: []string{"note"}}},
+		CheckList:  []*dbmodel.Constraint{{Name: "orders_id_check", Expression: "id > 0"}},
+		FKList: []*dbmodel.ForeignKey{{
+			Name:          "orders_customer_fk",
+			ColumnList:    []string{"customer_id"},
+			RefSchema:     "public",
+			RefTable:      "customer",
+			RefColumnList: []string{"id"},
+			OnDelete:      dbmodel.Cascade,
+			OnUpdate:      dbmodel.NoAction,
+		}},
+	}}
+
+	data, err := snapshotmodel.Encode(tableList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := snapshotmodel.Decode(data)
+	if err != nil {
+		t.Fatalf("не удалось разобрать снимок: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, tableList) {
+		t.Errorf("ожидалось: %+v, получено: %+v", tableList, decoded)
+	}
+
+	//формат снимка не должен меняться вместе с dbmodel: от него зависят сохранённые снимки и их отпечатки
+	const expected = `[{"name":"t","kind":"table","columnList":[{"name":"id","type":"integer","isPK":true}]}]`
+	if data, _ = snapshotmodel.Encode([]*dbmodel.Table{{
+		Name:       "t",
+		Kind:       dbmodel.KindTable,
+		ColumnList: []*dbmodel.Column{{Name: "id", Type: "integer", IsPK: true}},
+	}}); string(data) != expected {
+		t.Errorf("ожидалось: %s, получено: %s", expected, data)
+	}
+}
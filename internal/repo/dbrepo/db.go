@@ -4,14 +4,19 @@ import (
 	"context"
 	"datapoint/internal/model/dbmodel"
 	"datapoint/internal/service/dbservice"
+	"datapoint/internal/service/snapshotservice"
 	"datapoint/pkg/database"
+	"encoding/json"
 )
 
 type repo struct {
 	db *database.Database
 }
 
-var _ dbservice.DBRepo = (*repo)(nil)
+var (
+	_ dbservice.DBRepo       = (*repo)(nil)
+	_ snapshotservice.DBRepo = (*repo)(nil)
+)
 
 var columns = []string{
 	"id",
@@ -22,6 +27,19 @@ var columns = []string{
 	"password",
 	"db_name",
 	"driver",
+	"function_list",
+	"allow_write",
+	"path",
+	"read_only",
+	"schema_list",
+	"ssl_mode",
+	"ssl_root_cert",
+	"ssl_cert",
+	"ssl_key",
+	"ssh",
+	"params",
+	"conn_string",
+	"pool",
 }
 
 func (r *repo) GetList(ctx context.Context) ([]*dbmodel.DB, error) {
@@ -36,29 +54,118 @@ func (r *repo) GetList(ctx context.Context) ([]*dbmodel.DB, error) {
 
 	var list []*dbmodel.DB
 	for rows.Next() {
-		d := new(dbmodel.DB)
+		var (
+			d                                          = new(dbmodel.DB)
+			functionList, path, schemaList             *string
+			sslMode, sslRootCert, sslCert, sslKey, ssh *string
+			params, connString, pool                   *string
+		)
 
 		if err = rows.Scan(
-			d.ID,
-			d.Info.Name,
-			d.Info.Config.Host,
-			d.Info.Config.Port,
-			d.Info.Config.User,
-			d.Info.Config.Password,
-			d.Info.Config.Name,
-			d.Info.Config.Driver,
+			&d.ID,
+			&d.Info.Name,
+			&d.Info.Config.Host,
+			&d.Info.Config.Port,
+			&d.Info.Config.User,
+			&d.Info.Config.Password,
+			&d.Info.Config.Name,
+			&d.Info.Config.Driver,
+			&functionList,
+			&d.Info.AllowWrite,
+			&path,
+			&d.Info.Config.ReadOnly,
+			&schemaList,
+			&sslMode,
+			&sslRootCert,
+			&sslCert,
+			&sslKey,
+			&ssh,
+			&params,
+			&connString,
+			&pool,
 		); err != nil {
 			return nil, err
 		}
 
+		for _, v := range [...]struct {
+			src *string
+			dst *string
+		}{
+			{path, &d.Info.Config.Path},
+			{sslMode, &d.Info.Config.SSLMode},
+			{sslRootCert, &d.Info.Config.SSLRootCert},
+			{sslCert, &d.Info.Config.SSLCert},
+			{sslKey, &d.Info.Config.SSLKey},
+			{connString, &d.Info.Config.ConnString},
+		} {
+			if v.src != nil {
+				*v.dst = *v.src
+			}
+		}
+
+		if functionList != nil {
+			if err = json.Unmarshal([]byte(*functionList), &d.Info.FunctionList); err != nil {
+				return nil, err
+			}
+		}
+
+		if schemaList != nil {
+			if err = json.Unmarshal([]byte(*schemaList), &d.Info.SchemaList); err != nil {
+				return nil, err
+			}
+		}
+
+		if ssh != nil {
+			if err = json.Unmarshal([]byte(*ssh), &d.Info.Config.SSH); err != nil {
+				return nil, err
+			}
+		}
+
+		if params != nil {
+			if err = json.Unmarshal([]byte(*params), &d.Info.Config.Params); err != nil {
+				return nil, err
+			}
+		}
+
+		if pool != nil {
+			if err = json.Unmarshal([]byte(*pool), &d.Info.Config.Pool); err != nil {
+				return nil, err
+			}
+		}
+
 		list = append(list, d)
 	}
 
 	return list, nil
 }
 
-func (r *repo) Add(ctx context.Context, d dbmodel.DB) error {
-	_, err := r.db.B.
+func (r *repo) Add(ctx context.Context, d *dbmodel.DB) error {
+	functionList, err := marshalList(d.Info.FunctionList)
+	if err != nil {
+		return err
+	}
+
+	schemaList, err := marshalList(d.Info.SchemaList)
+	if err != nil {
+		return err
+	}
+
+	ssh, err := marshalJSON(d.Info.Config.SSH, d.Info.Config.SSH == nil)
+	if err != nil {
+		return err
+	}
+
+	params, err := marshalJSON(d.Info.Config.Params, len(d.Info.Config.Params) == 0)
+	if err != nil {
+		return err
+	}
+
+	pool, err := marshalJSON(d.Info.Config.Pool, d.Info.Config.Pool == database.PoolConfig{})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.B.
 		Insert("database").
 		Columns(columns...).
 		Values(
@@ -70,13 +177,51 @@ func (r *repo) Add(ctx context.Context, d dbmodel.DB) error {
 			d.Info.Config.Password,
 			d.Info.Config.Name,
 			d.Info.Config.Driver,
+			functionList,
+			d.Info.AllowWrite,
+			nullString(d.Info.Config.Path),
+			d.Info.Config.ReadOnly,
+			schemaList,
+			nullString(d.Info.Config.SSLMode),
+			nullString(d.Info.Config.SSLRootCert),
+			nullString(d.Info.Config.SSLCert),
+			nullString(d.Info.Config.SSLKey),
+			ssh,
+			params,
+			nullString(d.Info.Config.ConnString),
+			pool,
 		).
 		ExecContext(ctx)
 	return err
 }
 
-func (r *repo) Edit(ctx context.Context, d dbmodel.DB) error {
-	_, err := r.db.B.
+func (r *repo) Edit(ctx context.Context, d *dbmodel.DB) error {
+	functionList, err := marshalList(d.Info.FunctionList)
+	if err != nil {
+		return err
+	}
+
+	schemaList, err := marshalList(d.Info.SchemaList)
+	if err != nil {
+		return err
+	}
+
+	ssh, err := marshalJSON(d.Info.Config.SSH, d.Info.Config.SSH == nil)
+	if err != nil {
+		return err
+	}
+
+	params, err := marshalJSON(d.Info.Config.Params, len(d.Info.Config.Params) == 0)
+	if err != nil {
+		return err
+	}
+
+	pool, err := marshalJSON(d.Info.Config.Pool, d.Info.Config.Pool == database.PoolConfig{})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.B.
 		Update("database").
 		Set("name", d.Info.Name).
 		Set("host", d.Info.Config.Host).
@@ -85,11 +230,38 @@ func (r *repo) Edit(ctx context.Context, d dbmodel.DB) error {
 		Set("password", d.Info.Config.Password).
 		Set("db_name", d.Info.Config.Name).
 		Set("driver", d.Info.Config.Driver).
+		Set("function_list", functionList).
+		Set("allow_write", d.Info.AllowWrite).
+		Set("path", nullString(d.Info.Config.Path)).
+		Set("read_only", d.Info.Config.ReadOnly).
+		Set("schema_list", schemaList).
+		Set("ssl_mode", nullString(d.Info.Config.SSLMode)).
+		Set("ssl_root_cert", nullString(d.Info.Config.SSLRootCert)).
+		Set("ssl_cert", nullString(d.Info.Config.SSLCert)).
+		Set("ssl_key", nullString(d.Info.Config.SSLKey)).
+		Set("ssh", ssh).
+		Set("params", params).
+		Set("conn_string", nullString(d.Info.Config.ConnString)).
+		Set("pool", pool).
 		Where("id = ?", d.ID).
 		ExecContext(ctx)
 	return err
 }
 
+func (r *repo) Exists(ctx context.Context, id string) (bool, error) {
+	rows, err := r.db.B.
+		Select("1").
+		From("database").
+		Where("id = ?", id).
+		QueryContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return rows.Next(), rows.Err()
+}
+
 func (r *repo) Delete(ctx context.Context, id string) error {
 	_, err := r.db.B.
 		Delete("database").
@@ -98,6 +270,42 @@ func (r *repo) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+func marshalList(list []string) (*string, error) {
+	if list == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(data)
+	return &s, nil
+}
+
+// marshalJSON сохраняет настройку в JSON; пустая настройка хранится как NULL.
+func marshalJSON(v any, empty bool) (*string, error) {
+	if empty {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(data)
+	return &s, nil
+}
+
 func New(db *database.Database) *repo {
 	return &repo{db: db}
 }
+
+func nullString(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,54 @@
+package dbrepo_test
+
+import (
+	"context"
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/repo/dbrepo"
+	"datapoint/migration"
+	"datapoint/pkg/database"
+	"path/filepath"
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	db, err := database.New("sqlite3", filepath.Join(t.TempDir(), "datapoint.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = migration.FromFile(db, "../../../migration/migration.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	const id = "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a002"
+
+	var (
+		r   = dbrepo.New(db)
+		ctx = context.Background()
+	)
+
+	if err = r.Add(ctx, &dbmodel.DB{ID: id, Info: dbmodel.Info{
+		Name:   "test",
+		Config: dbmodel.Config{Driver: dbmodel.SQLite, Path: "test.db"},
+	}}); err != nil {
+		t.Fatalf("не удалось сохранить базу данных: %s", err)
+	}
+
+	if exists, err := r.Exists(ctx, id); err != nil || !exists {
+		t.Errorf("ожидалось, что база данных сохранена, получено: %t, %v", exists, err)
+	}
+
+	if err = r.Delete(ctx, id); err != nil {
+		t.Fatalf("не удалось удалить базу данных: %s", err)
+	}
+
+	var list []*dbmodel.DB
+	if list, err = r.GetList(ctx); err != nil || len(list) != 0 {
+		t.Errorf("ожидалось, что база данных удалена, получено: %d, %v", len(list), err)
+	}
+
+	if exists, err := r.Exists(ctx, id); err != nil || exists {
+		t.Errorf("ожидалось, что базы данных нет, получено: %t, %v", exists, err)
+	}
+}
@@ -0,0 +1,211 @@
+package historyrepo
+
+import (
+	"context"
+	"database/sql"
+	"datapoint/internal/model/historymodel"
+	"datapoint/internal/model/querymodel"
+	"datapoint/internal/service/queryservice"
+	"datapoint/pkg/database"
+	"encoding/json"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+type repo struct {
+	db *database.Database
+}
+
+var _ queryservice.HistoryRepo = (*repo)(nil)
+
+var columns = []string{
+	"id",
+	"database_id",
+	"query_type",
+	"info",
+	"query",
+	"args",
+	"duration",
+	"row_count",
+	"error",
+	"caller",
+	"created_at",
+	"redacted",
+}
+
+func (r *repo) Add(ctx context.Context, e historymodel.Entry) error {
+	var (
+		queryType = e.Info.Type
+		info      []byte
+		err       error
+	)
+	if e.Raw {
+		queryType = historymodel.Raw
+	} else if info, err = querymodel.Encode(e.Info); err != nil {
+		return err
+	}
+
+	var args *string
+	if e.Args != nil {
+		var data []byte
+		if data, err = json.Marshal(e.Args); err != nil {
+			return err
+		}
+		s := string(data)
+		args = &s
+	}
+
+	_, err = r.db.B.
+		Insert("query_history").
+		Columns(columns...).
+		Values(
+			e.ID,
+			e.DBID,
+			queryType,
+			string(info),
+			e.Query,
+			args,
+			int64(e.Duration),
+			e.RowCount,
+			nullString(e.Error),
+			nullString(e.Caller),
+			e.CreatedAt,
+			e.Redacted,
+		).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *repo) GetList(ctx context.Context, f historymodel.Filter) ([]*historymodel.Entry, error) {
+	b := r.db.B.
+		Select(columns...).
+		From("query_history").
+		OrderBy("created_at DESC")
+
+	if len(f.DBID) != 0 {
+		b = b.Where(sq.Eq{"database_id": f.DBID})
+	}
+
+	if len(f.Type) != 0 {
+		b = b.Where(sq.Eq{"query_type": f.Type})
+	}
+
+	if f.Failed != nil {
+		if *f.Failed {
+			b = b.Where(sq.NotEq{"error": nil})
+		} else {
+			b = b.Where(sq.Eq{"error": nil})
+		}
+	}
+
+	if f.From != nil {
+		b = b.Where(sq.GtOrEq{"created_at": *f.From})
+	}
+
+	if f.To != nil {
+		b = b.Where(sq.Lt{"created_at": *f.To})
+	}
+
+	if f.Limit != 0 {
+		b = b.Limit(f.Limit)
+	}
+
+	if f.Offset != 0 {
+		b = b.Offset(f.Offset)
+	}
+
+	rows, err := b.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var list []*historymodel.Entry
+	for rows.Next() {
+		var e *historymodel.Entry
+		if e, err = scan(rows); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
+
+func (r *repo) GetByID(ctx context.Context, id string) (*historymodel.Entry, error) {
+	rows, err := r.db.B.
+		Select(columns...).
+		From("query_history").
+		Where(sq.Eq{"id": id}).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("записи истории не существует")
+	}
+
+	return scan(rows)
+}
+
+func scan(rows *sql.Rows) (*historymodel.Entry, error) {
+	var (
+		e                     = new(historymodel.Entry)
+		queryType, info       string
+		args, errText, caller sql.NullString
+		duration              int64
+	)
+
+	if err := rows.Scan(
+		&e.ID,
+		&e.DBID,
+		&queryType,
+		&info,
+		&e.Query,
+		&args,
+		&duration,
+		&e.RowCount,
+		&errText,
+		&caller,
+		&e.CreatedAt,
+		&e.Redacted,
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if e.Raw = queryType == historymodel.Raw; !e.Raw {
+		if e.Info, err = querymodel.Decode([]byte(info)); err != nil {
+			return nil, err
+		}
+	}
+
+	if args.Valid {
+		if err = json.Unmarshal([]byte(args.String), &e.Args); err != nil {
+			return nil, err
+		}
+	}
+
+	e.Duration = time.Duration(duration)
+	e.Error = errText.String
+	e.Caller = caller.String
+
+	return e, nil
+}
+
+func nullString(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return &s
+}
+
+func New(db *database.Database) *repo {
+	return &repo{db: db}
+}
@@ -0,0 +1,116 @@
+package historyrepo_test
+
+import (
+	"context"
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/historymodel"
+	"datapoint/internal/model/querymodel"
+	"datapoint/internal/repo/historyrepo"
+	"datapoint/migration"
+	"datapoint/pkg/database"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedacted(t *testing.T) {
+	db, err := database.New("sqlite3", filepath.Join(t.TempDir(), "datapoint.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = migration.FromFile(db, "../../../migration/migration.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	info := querymodel.Info{
+		Type:  querymodel.Update,
+		Table: &querymodel.Table{TableKey: querymodel.TableKey{Name: "customer"}},
+		Columns: []*querymodel.Column{
+			{Column: dbmodel.Column{Name: "password"}, Value: "секретный-пароль"},
+		},
+		Where: []*querymodel.Column{
+			{Column: dbmodel.Column{Name: "email"}, Value: "anna@example.com"},
+		},
+	}
+
+	r := historyrepo.New(db)
+	ctx := context.Background()
+
+	if err = r.Add(ctx, historymodel.Entry{
+		ID:        "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a001",
+		DBID:      "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a002",
+		Info:      info.Redact(),
+		Query:     `UPDATE "customer" SET "password" = $1 WHERE "email" = $2`,
+		Redacted:  true,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("не удалось сохранить запись: %s", err)
+	}
+
+	if info.Columns[0].Value == nil || info.Where[0].Value == nil {
+		t.Errorf("Redact не должен менять исходный запрос")
+	}
+
+	var stored string
+	if err = db.QueryRowContext(ctx, "SELECT info FROM query_history").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range [...]string{"секретный-пароль", "anna@example.com"} {
+		if strings.Contains(stored, value) {
+			t.Errorf("значение %s не должно попасть в историю: %s", value, stored)
+		}
+	}
+
+	e, err := r.GetByID(ctx, "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a001")
+	if err != nil {
+		t.Fatalf("не удалось прочитать запись: %s", err)
+	}
+
+	if !e.Redacted || e.Args != nil {
+		t.Errorf("ожидалась запись со скрытыми значениями, получено: %+v", e)
+	}
+
+	if len(e.Info.Columns) != 1 || e.Info.Columns[0].Name != "password" || e.Info.Columns[0].Value != nil ||
+		len(e.Info.Where) != 1 || e.Info.Where[0].Name != "email" || e.Info.Where[0].Value != nil {
+		t.Errorf("ожидались колонки без значений, получено: %+v, %+v", e.Info.Columns, e.Info.Where)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	db, err := database.New("sqlite3", filepath.Join(t.TempDir(), "datapoint.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = migration.FromFile(db, "../../../migration/migration.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := historyrepo.New(db)
+	ctx := context.Background()
+
+	if err = r.Add(ctx, historymodel.Entry{
+		ID:        "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a003",
+		DBID:      "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a002",
+		Query:     "SELECT 1",
+		Raw:       true,
+		Duration:  time.Second,
+		RowCount:  1,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("не удалось сохранить запись: %s", err)
+	}
+
+	list, err := r.GetList(ctx, historymodel.Filter{Type: historymodel.Raw})
+	if err != nil {
+		t.Fatalf("не удалось прочитать историю: %s", err)
+	}
+
+	if len(list) != 1 || !list[0].Raw || list[0].Query != "SELECT 1" || list[0].RowCount != 1 || list[0].Duration != time.Second {
+		t.Errorf("ожидалась запись произвольного запроса, получено: %+v", list)
+	}
+}
@@ -0,0 +1,144 @@
+package snapshotrepo
+
+import (
+	"context"
+	"datapoint/internal/model/snapshotmodel"
+	"datapoint/internal/service/dbservice"
+	"datapoint/internal/service/snapshotservice"
+	"datapoint/pkg/database"
+	sq "github.com/Masterminds/squirrel"
+)
+
+type repo struct {
+	db *database.Database
+}
+
+var (
+	_ snapshotservice.SnapshotRepo = (*repo)(nil)
+	_ dbservice.SnapshotRepo       = (*repo)(nil)
+)
+
+// список таблиц читается только для отдельного снимка
+var columns = []string{
+	"id",
+	"database_id",
+	"version",
+	"hash",
+	"created_at",
+}
+
+func (r *repo) Add(ctx context.Context, s *snapshotmodel.Snapshot) error {
+	tableList, err := snapshotmodel.Encode(s.TableList)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.B.
+		Insert("schema_snapshot").
+		Columns(append(columns, "table_list")...).
+		Values(
+			s.ID,
+			s.DBID,
+			s.Version,
+			s.Hash,
+			s.CreatedAt,
+			string(tableList),
+		).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *repo) GetList(ctx context.Context, dbID string) ([]*snapshotmodel.Snapshot, error) {
+	rows, err := r.db.B.
+		Select(columns...).
+		From("schema_snapshot").
+		Where(sq.Eq{"database_id": dbID}).
+		OrderBy("version DESC").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var list []*snapshotmodel.Snapshot
+	for rows.Next() {
+		s := new(snapshotmodel.Snapshot)
+		if err = rows.Scan(&s.ID, &s.DBID, &s.Version, &s.Hash, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+
+	return list, rows.Err()
+}
+
+// Last возвращает последний снимок без списка таблиц или nil, если снимков ещё нет.
+func (r *repo) Last(ctx context.Context, dbID string) (*snapshotmodel.Snapshot, error) {
+	rows, err := r.db.B.
+		Select(columns...).
+		From("schema_snapshot").
+		Where(sq.Eq{"database_id": dbID}).
+		OrderBy("version DESC").
+		Limit(1).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	s := new(snapshotmodel.Snapshot)
+	if err = rows.Scan(&s.ID, &s.DBID, &s.Version, &s.Hash, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *repo) GetByVersion(ctx context.Context, dbID string, version int) (*snapshotmodel.Snapshot, error) {
+	rows, err := r.db.B.
+		Select(append(columns, "table_list")...).
+		From("schema_snapshot").
+		Where(sq.Eq{"database_id": dbID, "version": version}).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, snapshotmodel.ErrNotFound
+	}
+
+	var (
+		s         = new(snapshotmodel.Snapshot)
+		tableList string
+	)
+	if err = rows.Scan(&s.ID, &s.DBID, &s.Version, &s.Hash, &s.CreatedAt, &tableList); err != nil {
+		return nil, err
+	}
+
+	if s.TableList, err = snapshotmodel.Decode([]byte(tableList)); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// DeleteByDB удаляет все снимки базы данных; вызывается в транзакции удаления самой базы данных.
+func (r *repo) DeleteByDB(ctx context.Context, dbID string) error {
+	_, err := r.db.B.
+		Delete("schema_snapshot").
+		Where(sq.Eq{"database_id": dbID}).
+		ExecContext(ctx)
+	return err
+}
+
+func New(db *database.Database) *repo {
+	return &repo{db: db}
+}
@@ -0,0 +1,65 @@
+package snapshotrepo_test
+
+import (
+	"context"
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/snapshotmodel"
+	"datapoint/internal/repo/dbrepo"
+	"datapoint/internal/repo/snapshotrepo"
+	"datapoint/migration"
+	"datapoint/pkg/database"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeleteByDB(t *testing.T) {
+	db, err := database.New("sqlite3", filepath.Join(t.TempDir(), "datapoint.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = migration.FromFile(db, "../../../migration/migration.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		r      = snapshotrepo.New(db)
+		ctx    = context.Background()
+		idList = [...]string{"6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a002", "6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a004"}
+	)
+
+	for i, id := range idList {
+		if err = dbrepo.New(db).Add(ctx, &dbmodel.DB{ID: id, Info: dbmodel.Info{
+			Name:   "test",
+			Config: dbmodel.Config{Driver: dbmodel.SQLite, Path: "test.db"},
+		}}); err != nil {
+			t.Fatalf("не удалось сохранить базу данных: %s", err)
+		}
+
+		if err = r.Add(ctx, &snapshotmodel.Snapshot{
+			ID:        fmt.Sprintf("6f1d5c9e-8a53-4a37-9c0a-0d3c41b2a10%d", i),
+			DBID:      id,
+			Version:   1,
+			Hash:      "hash",
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatalf("не удалось сохранить снимок схемы: %s", err)
+		}
+	}
+
+	if err = r.DeleteByDB(ctx, idList[0]); err != nil {
+		t.Fatalf("не удалось удалить снимки схемы: %s", err)
+	}
+
+	if _, err = r.GetByVersion(ctx, idList[0], 1); !errors.Is(err, snapshotmodel.ErrNotFound) {
+		t.Errorf("ожидалась ошибка %q, получено: %v", snapshotmodel.ErrNotFound, err)
+	}
+
+	if _, err = r.GetByVersion(ctx, idList[1], 1); err != nil {
+		t.Errorf("снимок другой базы данных не должен удаляться, получено: %v", err)
+	}
+}
@@ -2,27 +2,49 @@ package dbservice
 
 import (
 	"context"
+	"database/sql"
+	"datapoint/config"
 	"datapoint/internal/model/dbmodel"
 	"datapoint/pkg/database"
 	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"sync"
 )
 
 type DBRepo interface {
 	GetList(ctx context.Context) ([]*dbmodel.DB, error)
-	Add(ctx context.Context, db dbmodel.DB) error
-	Edit(ctx context.Context, db dbmodel.DB) error
+	Add(ctx context.Context, db *dbmodel.DB) error
+	Edit(ctx context.Context, db *dbmodel.DB) error
 	Delete(ctx context.Context, id string) error
 }
 
+type SnapshotRepo interface {
+	DeleteByDB(ctx context.Context, dbID string) error
+}
+
 type service struct {
-	r      DBRepo
-	tx     database.TxManager
+	r            DBRepo
+	snapshotRepo SnapshotRepo
+	tx           database.TxManager
+	cfg          config.Health
+	cacheCfg     config.Metadata
+	sqlite       config.SQLite
+	diagnose     config.Diagnose
+	metaDSN      string //база данных самого сервиса, которую нельзя подключить как SQLite
+	cancel       context.CancelFunc
+
+	mu     sync.RWMutex //базы данных добавляются из запросов и читаются фоновой проверкой
 	dbList map[string]*dbmodel.DB
+
+	cacheMu sync.Mutex
+	cache   map[string]*cacheEntry
 }
 
 func (s *service) GetList() []*dbmodel.DB {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	list := make([]*dbmodel.DB, 0, len(s.dbList))
 	for _, d := range s.dbList {
 		list = append(list, d)
@@ -34,7 +56,9 @@ func (s *service) GetByID(id string) (*dbmodel.DB, error) {
 	zap.S().Info("попытка получить базу данных по идентификатору",
 		zap.String("id", id))
 
+	s.mu.RLock()
 	db, ok := s.dbList[id]
+	s.mu.RUnlock()
 	if !ok {
 		err := errors.New("базы данных не существует")
 		zap.S().Error(err, zap.String("id", id))
@@ -48,6 +72,11 @@ func (s *service) GetByID(id string) (*dbmodel.DB, error) {
 func (s *service) Add(ctx context.Context, info dbmodel.Info) (string, error) {
 	zap.S().Info("попытка добавить базу данных")
 
+	if err := info.Config.CheckPath(s.sqlite.Dir, s.metaDSN); err != nil {
+		zap.S().Error(err)
+		return "", err
+	}
+
 	db, err := dbmodel.New(info)
 	if err != nil {
 		err = fmt.Errorf("не удалось подключиться к базе данных: %s", err)
@@ -55,19 +84,45 @@ func (s *service) Add(ctx context.Context, info dbmodel.Info) (string, error) {
 		return "", err
 	}
 
-	if err = s.r.Add(ctx, *db); err != nil {
+	if err = s.r.Add(ctx, db); err != nil {
 		db.Close()
 		err = fmt.Errorf("не удалось сохранить базу данных: %s", err)
 		zap.S().Error(err)
 		return "", err
 	}
 
+	s.mu.Lock()
 	s.dbList[db.ID] = db
+	s.mu.Unlock()
 
 	zap.S().Info("база данных успешно добавлена")
 	return db.ID, nil
 }
 
+// Test проверяет подключение по настройкам info, не сохраняя базу данных.
+func (s *service) Test(ctx context.Context, info dbmodel.Info) dbmodel.Diagnosis {
+	zap.S().Info("попытка проверить подключение к базе данных")
+
+	if s.diagnose.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.diagnose.Timeout)
+		defer cancel()
+	}
+
+	if err := info.Config.CheckPath(s.sqlite.Dir, s.metaDSN); err != nil {
+		zap.S().Info("подключение к базе данных не прошло проверку")
+		return dbmodel.Diagnosis{StepList: []*dbmodel.Step{{Name: dbmodel.StepConfig, Status: dbmodel.StepFailed, Error: err.Error()}}}
+	}
+
+	d := dbmodel.Diagnose(ctx, info.Config)
+	if d.OK {
+		zap.S().Info("подключение к базе данных успешно проверено")
+	} else {
+		zap.S().Info("подключение к базе данных не прошло проверку")
+	}
+	return d
+}
+
 func (s *service) Edit(ctx context.Context, info dbmodel.Info, id string) error {
 	zap.S().Info("попытка отредактировать базу данных", zap.String("id", id))
 
@@ -76,8 +131,15 @@ func (s *service) Edit(ctx context.Context, info dbmodel.Info, id string) error
 		return err
 	}
 
+	if err = info.Config.CheckPath(s.sqlite.Dir, s.metaDSN); err != nil {
+		zap.S().Error(err, zap.String("id", id))
+		return err
+	}
+
+	info.Config.KeepSecrets(db.GetInfo().Config)
+
 	if err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
-		err := s.r.Edit(ctx, *db)
+		err := s.r.Edit(ctx, &dbmodel.DB{ID: db.ID, Info: info})
 		if err != nil {
 			err = fmt.Errorf("не удалось отредактировать базу данных: %s", err)
 			zap.S().Error(err)
@@ -94,6 +156,7 @@ func (s *service) Edit(ctx context.Context, info dbmodel.Info, id string) error
 	}); err != nil {
 		return err
 	}
+	s.invalidate(id)
 
 	zap.S().Info("база данных успешно отредактирована", zap.String("id", id))
 	return nil
@@ -107,59 +170,86 @@ func (s *service) Delete(ctx context.Context, id string) error {
 		return err
 	}
 
-	if err = s.r.Delete(ctx, id); err != nil {
+	if err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		if err := s.snapshotRepo.DeleteByDB(ctx, id); err != nil {
+			return err
+		}
+		return s.r.Delete(ctx, id)
+	}); err != nil {
 		err = fmt.Errorf("не удалось удалить базу данных: %s", err)
 		zap.S().Error(err, zap.String("id", id))
 		return err
 	}
 
-	db.Close()
+	s.mu.Lock()
 	delete(s.dbList, db.ID)
+	s.mu.Unlock()
+	s.invalidate(db.ID)
+	db.Close()
 
 	zap.S().Info("база данных успешно удалена", zap.String("id", id))
 	return nil
 }
 
-func (s *service) TableList(ctx context.Context, id string) ([]*dbmodel.Table, error) {
-	zap.S().Info("попытка получить таблицы базы данных", zap.String("id", id))
+func (s *service) SchemaList(ctx context.Context, id string) ([]string, error) {
+	zap.S().Info("попытка получить схемы базы данных", zap.String("id", id))
 
 	db, err := s.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	var list []*dbmodel.Table
-	if list, err = db.TableList(ctx); err != nil {
-		err = fmt.Errorf("не удалось получить таблицы базы данных: %s", err)
+	var list []string
+	if list, err = db.SchemaList(ctx); err != nil {
+		err = fmt.Errorf("не удалось получить схемы базы данных: %s", err)
 		zap.S().Error(err, zap.String("id", id))
 		return nil, err
 	}
 
-	zap.S().Info("таблицы базы данных успешно получены", zap.String("id", id))
+	zap.S().Info("схемы базы данных успешно получены", zap.String("id", id))
 	return list, nil
 }
 
-func (s *service) FunctionList(ctx context.Context, id string) ([]*dbmodel.Function, error) {
-	zap.S().Info("попытка получить функции базы данных", zap.String("id", id))
+func (s *service) Stats(id string) (sql.DBStats, error) {
+	zap.S().Info("попытка получить состояние пула соединений базы данных", zap.String("id", id))
 
 	db, err := s.GetByID(id)
 	if err != nil {
-		return nil, err
+		return sql.DBStats{}, err
 	}
 
-	var list []*dbmodel.Function
-	if list, err = db.FunctionList(ctx); err != nil {
-		err = fmt.Errorf("не удалось получить функции базы данных: %s", err)
+	var stats sql.DBStats
+	if stats, err = db.Stats(); err != nil {
+		err = fmt.Errorf("не удалось подключиться к базе данных: %s", err)
 		zap.S().Error(err, zap.String("id", id))
-		return nil, err
+		return sql.DBStats{}, err
 	}
 
-	zap.S().Info("функции базы данных успешно получено", zap.String("id", id))
-	return list, nil
+	zap.S().Info("состояние пула соединений базы данных успешно получено", zap.String("id", id))
+	return stats, nil
 }
 
-func New(r DBRepo, tx database.TxManager) (*service, error) {
-	s := &service{r: r, tx: tx, dbList: make(map[string]*dbmodel.DB)}
+func (s *service) Close() {
+	s.cancel()
+}
+
+func New(r DBRepo, snapshotRepo SnapshotRepo, tx database.TxManager, cfg config.Health, cacheCfg config.Metadata,
+	sqlite config.SQLite, diagnose config.Diagnose, metaDB config.DB) (*service, error) {
+	s := &service{
+		r:            r,
+		snapshotRepo: snapshotRepo,
+		tx:           tx,
+		cfg:          cfg,
+		cacheCfg:     cacheCfg,
+		sqlite:       sqlite,
+		diagnose:     diagnose,
+		dbList:       make(map[string]*dbmodel.DB),
+		cache:        make(map[string]*cacheEntry),
+	}
+
+	if metaDB.Driver == database.Sqlite3 {
+		s.metaDSN = metaDB.DSN
+	}
 
 	list, err := r.GetList(context.Background())
 	if err != nil {
@@ -175,5 +265,11 @@ func New(r DBRepo, tx database.TxManager) (*service, error) {
 		s.dbList[d.ID] = d
 	}
 
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	if cfg.Interval > 0 {
+		go s.healthCheck(ctx)
+	}
+
 	return s, nil
 }
@@ -0,0 +1,110 @@
+package dbservice
+
+import (
+	"context"
+	"datapoint/config"
+	"datapoint/internal/model/dbmodel"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// retry - отсрочка следующей проверки недоступной базы данных.
+type retry struct {
+	failures int
+	next     time.Time
+}
+
+// healthCheck периодически проверяет все базы данных. Недоступные базы данных проверяются
+// (а значит, и переподключаются) с экспоненциально растущим интервалом.
+func (s *service) healthCheck(ctx context.Context) {
+	t := time.NewTicker(s.cfg.Interval)
+	defer t.Stop()
+
+	retries := make(map[string]*retry)
+	s.checkAll(ctx, time.Now(), retries)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			s.checkAll(ctx, now, retries)
+		}
+	}
+}
+
+func (s *service) checkAll(ctx context.Context, now time.Time, retries map[string]*retry) {
+	var (
+		list   []*dbmodel.DB
+		exists = make(map[string]bool)
+	)
+	for _, db := range s.GetList() {
+		exists[db.ID] = true
+		if r, ok := retries[db.ID]; ok && now.Before(r.next) {
+			continue
+		}
+		list = append(list, db)
+	}
+
+	//удалённые базы данных
+	for id := range retries {
+		if !exists[id] {
+			delete(retries, id)
+		}
+	}
+
+	timeout := s.cfg.Timeout
+	if timeout <= 0 {
+		timeout = s.cfg.Interval
+	}
+
+	healthList := make([]dbmodel.Health, len(list))
+
+	var wg sync.WaitGroup
+	for i, db := range list {
+		wg.Add(1)
+		go func(i int, db *dbmodel.DB) {
+			defer wg.Done()
+
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			healthList[i] = db.Ping(pingCtx, s.cfg.Degraded)
+		}(i, db)
+	}
+	wg.Wait()
+
+	for i, db := range list {
+		h := healthList[i]
+		r, failed := retries[db.ID]
+
+		if h.Status != dbmodel.Down {
+			if failed {
+				zap.S().Info("подключение к базе данных восстановлено", zap.String("id", db.ID))
+				delete(retries, db.ID)
+			}
+			continue
+		}
+
+		if !failed {
+			zap.S().Error(fmt.Errorf("база данных недоступна: %s", h.Error), zap.String("id", db.ID))
+			r = new(retry)
+			retries[db.ID] = r
+		}
+		r.failures++
+		r.next = now.Add(backoff(s.cfg, r.failures))
+	}
+}
+
+// backoff удваивает интервал проверки после каждой неудачи, но не больше cfg.MaxBackoff.
+func backoff(cfg config.Health, failures int) time.Duration {
+	d := cfg.Interval
+	for i := 1; i < failures && d < cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if cfg.MaxBackoff > 0 && d > cfg.MaxBackoff {
+		d = cfg.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,68 @@
+package dbservice
+
+import (
+	"context"
+	"datapoint/internal/model/dbmodel"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// cacheEntry - метаданные одной базы данных. Пока они загружаются, остальные запросы ждут
+// той же загрузки, а не читают information_schema параллельно.
+type cacheEntry struct {
+	mu sync.Mutex
+	m  *dbmodel.Metadata
+}
+
+// Metadata возвращает таблицы и функции базы данных из кеша, загружая их после истечения cfg.TTL.
+func (s *service) Metadata(ctx context.Context, id string) (*dbmodel.Metadata, error) {
+	return s.metadata(ctx, id, false)
+}
+
+// Refresh загружает метаданные базы данных заново, не дожидаясь истечения cfg.TTL.
+func (s *service) Refresh(ctx context.Context, id string) (*dbmodel.Metadata, error) {
+	return s.metadata(ctx, id, true)
+}
+
+func (s *service) metadata(ctx context.Context, id string, refresh bool) (*dbmodel.Metadata, error) {
+	db, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheMu.Lock()
+	e, ok := s.cache[id]
+	if !ok {
+		e = new(cacheEntry)
+		s.cache[id] = e
+	}
+	s.cacheMu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !refresh && e.m != nil && time.Since(e.m.LoadedAt) < s.cacheCfg.TTL {
+		return e.m, nil
+	}
+
+	zap.S().Info("попытка загрузить метаданные базы данных", zap.String("id", id))
+
+	var m *dbmodel.Metadata
+	if m, err = db.Metadata(ctx); err != nil {
+		zap.S().Error(err, zap.String("id", id))
+		return nil, err
+	}
+	e.m = m
+
+	zap.S().Info("метаданные базы данных успешно загружены", zap.String("id", id), zap.String("etag", m.ETag))
+	return m, nil
+}
+
+// invalidate сбрасывает метаданные после изменения или удаления базы данных. Загрузка, начатая
+// до сброса, сохраняет результат в уже удалённую запись, и он не попадает в кеш.
+func (s *service) invalidate(id string) {
+	s.cacheMu.Lock()
+	delete(s.cache, id)
+	s.cacheMu.Unlock()
+}
@@ -0,0 +1,310 @@
+package jobservice
+
+import (
+	"bufio"
+	"context"
+	"datapoint/config"
+	"datapoint/internal/model/jobmodel"
+	"datapoint/internal/model/querymodel"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type QueryService interface {
+	Execute(ctx context.Context, info querymodel.Info, id, qid string) (querymodel.QueryResult, error)
+}
+
+type service struct {
+	queryService QueryService
+	cfg          config.Job
+	console      config.Console
+
+	mu     sync.RWMutex
+	jobs   map[string]*jobmodel.Job
+	queue  chan string
+	cancel context.CancelFunc
+}
+
+func (s *service) Add(info querymodel.Info, id string) (string, error) {
+	zap.S().Info("попытка добавить задание")
+
+	j := &jobmodel.Job{
+		ID:        uuid.NewString(),
+		DBID:      id,
+		Info:      info,
+		Status:    jobmodel.Queued,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	s.jobs[j.ID] = j
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- j.ID:
+	default:
+		s.mu.Lock()
+		delete(s.jobs, j.ID)
+		s.mu.Unlock()
+
+		err := errors.New("очередь заданий переполнена")
+		zap.S().Error(err)
+		return "", err
+	}
+
+	zap.S().Info("задание успешно добавлено", zap.String("jid", j.ID))
+	return j.ID, nil
+}
+
+func (s *service) GetByID(jid string) (jobmodel.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[jid]
+	if !ok {
+		zap.S().Error(jobmodel.ErrNotFound, zap.String("jid", jid))
+		return jobmodel.Job{}, jobmodel.ErrNotFound
+	}
+
+	return *j, nil
+}
+
+func (s *service) GetList() []jobmodel.Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]jobmodel.Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		list = append(list, *j)
+	}
+	return list
+}
+
+func (s *service) Result(jid string, limit, offset uint64) (jobmodel.Page, error) {
+	zap.S().Info("попытка получить результат задания", zap.String("jid", jid))
+
+	j, err := s.GetByID(jid)
+	if err != nil {
+		return jobmodel.Page{}, err
+	}
+
+	if j.Status != jobmodel.Done {
+		err = fmt.Errorf("%w: задание в статусе %s", jobmodel.ErrNotDone, j.Status)
+		zap.S().Error(err, zap.String("jid", jid))
+		return jobmodel.Page{}, err
+	}
+
+	page := jobmodel.Page{RowCount: j.RowCount, Limit: limit, Offset: offset}
+	if page.Data, err = s.read(jid, limit, offset); err != nil {
+		//файл мог удалить cleanup после проверки статуса
+		if errors.Is(err, os.ErrNotExist) {
+			zap.S().Error(jobmodel.ErrNotFound, zap.String("jid", jid))
+			return jobmodel.Page{}, jobmodel.ErrNotFound
+		}
+		err = fmt.Errorf("не удалось прочитать результат задания: %s", err)
+		zap.S().Error(err, zap.String("jid", jid))
+		return jobmodel.Page{}, err
+	}
+
+	zap.S().Info("результат задания успешно получен", zap.String("jid", jid))
+	return page, nil
+}
+
+func (s *service) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case jid := <-s.queue:
+			s.run(ctx, jid)
+		}
+	}
+}
+
+func (s *service) run(ctx context.Context, jid string) {
+	s.mu.Lock()
+	j, ok := s.jobs[jid]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	startedAt := time.Now().UTC()
+	j.Status, j.StartedAt = jobmodel.Running, &startedAt
+	info, id := j.Info, j.DBID
+	s.mu.Unlock()
+
+	//результат целиком держится в памяти до записи на диск, поэтому выборка ограничена console.row_limit;
+	//лишняя строка читается, только чтобы узнать, что результат обрезан
+	limit := uint64(s.console.RowLimit)
+	if info.Type == querymodel.Select && limit > 0 && (info.Limit == 0 || info.Limit > limit) {
+		info.Limit = limit + 1
+	}
+
+	//идентификатор задания совпадает с идентификатором запроса, поэтому его можно отменить через queryservice
+	result, err := s.queryService.Execute(ctx, info, id, jid)
+	truncated := limit > 0 && uint64(len(result.Data)) > limit
+	if truncated {
+		result.Data, result.RowCount = result.Data[:limit], int64(limit)
+	}
+	if err == nil {
+		err = s.write(jid, result.Data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	finishedAt := time.Now().UTC()
+	expiresAt := finishedAt.Add(s.cfg.TTL)
+	j.FinishedAt, j.ExpiresAt = &finishedAt, &expiresAt
+
+	if err != nil {
+		j.Status, j.Error = jobmodel.Failed, err.Error()
+		zap.S().Error(fmt.Errorf("не удалось выполнить задание: %s", err), zap.String("jid", jid))
+		return
+	}
+
+	j.Status, j.RowCount, j.Truncated = jobmodel.Done, result.RowCount, truncated
+}
+
+func (s *service) path(jid string) string {
+	return filepath.Join(s.cfg.Dir, jid+".jsonl")
+}
+
+// write сохраняет результат построчно, чтобы страницы можно было читать без разбора всего файла.
+func (s *service) write(jid string, data []map[string]any) error {
+	f, err := os.Create(s.path(jid))
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	e := json.NewEncoder(w)
+	for _, row := range data {
+		if err = e.Encode(row); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *service) read(jid string, limit, offset uint64) ([]map[string]any, error) {
+	f, err := os.Open(s.path(jid))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var (
+		r    = bufio.NewReader(f)
+		data = make([]map[string]any, 0)
+	)
+
+	for i := uint64(0); limit == 0 || i < offset+limit; i++ {
+		var line []byte
+		if line, err = r.ReadBytes('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if i < offset {
+			continue
+		}
+
+		row := make(map[string]any)
+		if err = json.Unmarshal(line, &row); err != nil {
+			return nil, err
+		}
+		data = append(data, row)
+	}
+
+	return data, nil
+}
+
+// cleanup удаляет задания и их результаты с истёкшим сроком хранения.
+func (s *service) cleanup(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			s.mu.Lock()
+			for jid, j := range s.jobs {
+				if j.ExpiresAt == nil || j.ExpiresAt.After(now) {
+					continue
+				}
+
+				if err := os.Remove(s.path(jid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+					zap.S().Errorf("не удалось удалить результат задания: %s", err)
+				}
+				delete(s.jobs, jid)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *service) Close() {
+	s.cancel()
+}
+
+func New(queryService QueryService, cfg config.Job, console config.Console) (*service, error) {
+	if cfg.Workers <= 0 {
+		return nil, errors.New("в настройках заданий должен быть хотя бы один обработчик (job.workers)")
+	}
+
+	if cfg.QueueSize <= 0 {
+		return nil, errors.New("размер очереди заданий должен быть больше нуля (job.queue_size)")
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог для результатов заданий: %s", err)
+	}
+
+	//состояние заданий хранится в памяти, поэтому результаты прошлых запусков недоступны
+	old, err := filepath.Glob(filepath.Join(cfg.Dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range old {
+		_ = os.Remove(name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &service{
+		queryService: queryService,
+		cfg:          cfg,
+		console:      console,
+		jobs:         make(map[string]*jobmodel.Job),
+		queue:        make(chan string, cfg.QueueSize),
+		cancel:       cancel,
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		go s.work(ctx)
+	}
+
+	go s.cleanup(ctx)
+
+	return s, nil
+}
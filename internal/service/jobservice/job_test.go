@@ -0,0 +1,116 @@
+package jobservice
+
+import (
+	"context"
+	"datapoint/config"
+	"datapoint/internal/model/jobmodel"
+	"datapoint/internal/model/querymodel"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type queryService struct{ data []map[string]any }
+
+func (s queryService) Execute(context.Context, querymodel.Info, string, string) (querymodel.QueryResult, error) {
+	return querymodel.QueryResult{Data: s.data, RowCount: int64(len(s.data))}, nil
+}
+
+func TestResult(t *testing.T) {
+	data := []map[string]any{{"id": "a"}, {"id": "b"}, {"id": "c"}}
+
+	s, err := New(queryService{data: data}, config.Job{Workers: 1, QueueSize: 1, Dir: t.TempDir(), TTL: time.Hour}, config.Console{})
+	if err != nil {
+		t.Fatalf("не удалось создать сервис: %s", err)
+	}
+	defer s.Close()
+
+	var jid string
+	if jid, err = s.Add(querymodel.Info{Type: querymodel.Select}, ""); err != nil {
+		t.Fatalf("не удалось добавить задание: %s", err)
+	}
+
+	wait(s, jid)
+
+	tests := [...]struct {
+		limit, offset uint64
+		expected      []map[string]any
+	}{
+		{limit: 0, offset: 0, expected: data},
+		{limit: 2, offset: 0, expected: data[:2]},
+		{limit: 2, offset: 2, expected: data[2:]},
+		{limit: 2, offset: 5, expected: []map[string]any{}},
+	}
+
+	for _, test := range tests {
+		page, err := s.Result(jid, test.limit, test.offset)
+		if err != nil {
+			t.Fatalf("не удалось получить результат задания: %s", err)
+		}
+
+		if !reflect.DeepEqual(page.Data, test.expected) || page.RowCount != int64(len(data)) {
+			t.Errorf("limit=%d, offset=%d --> ожидалось: %v, получено: %v",
+				test.limit, test.offset, test.expected, page.Data)
+		}
+	}
+
+	//файл результата удалён cleanup после проверки статуса
+	if err = os.Remove(s.path(jid)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Result(jid, 0, 0); !errors.Is(err, jobmodel.ErrNotFound) {
+		t.Errorf("ожидалась ошибка %q, получено: %v", jobmodel.ErrNotFound, err)
+	}
+}
+
+func TestRowLimit(t *testing.T) {
+	data := []map[string]any{{"id": "a"}, {"id": "b"}, {"id": "c"}}
+
+	s, err := New(queryService{data: data}, config.Job{Workers: 1, QueueSize: 1, Dir: t.TempDir(), TTL: time.Hour},
+		config.Console{RowLimit: 2})
+	if err != nil {
+		t.Fatalf("не удалось создать сервис: %s", err)
+	}
+	defer s.Close()
+
+	if _, err = s.Result("unknown", 0, 0); !errors.Is(err, jobmodel.ErrNotFound) {
+		t.Errorf("ожидалась ошибка %q, получено: %v", jobmodel.ErrNotFound, err)
+	}
+
+	var jid string
+	if jid, err = s.Add(querymodel.Info{Type: querymodel.Select}, ""); err != nil {
+		t.Fatalf("не удалось добавить задание: %s", err)
+	}
+	wait(s, jid)
+
+	j, _ := s.GetByID(jid)
+	if !j.Truncated || j.RowCount != 2 {
+		t.Errorf("ожидался обрезанный до 2 строк результат, получено: truncated=%t, rowCount=%d", j.Truncated, j.RowCount)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := [...]config.Job{
+		{Workers: 0, QueueSize: 1},
+		{Workers: 1, QueueSize: 0},
+	}
+
+	for _, test := range tests {
+		test.Dir = t.TempDir()
+		if _, err := New(queryService{}, test, config.Console{}); err == nil {
+			t.Errorf("workers=%d, queueSize=%d --> ожидалась ошибка", test.Workers, test.QueueSize)
+		}
+	}
+}
+
+func wait(s *service, jid string) {
+	for i := 0; i < 100; i++ {
+		if j, _ := s.GetByID(jid); j.Status == jobmodel.Done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,41 +2,414 @@ package queryservice
 
 import (
 	"context"
+	"datapoint/config"
 	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/historymodel"
 	"datapoint/internal/model/querymodel"
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 type DBService interface {
 	GetByID(id string) (*dbmodel.DB, error)
+	Metadata(ctx context.Context, id string) (*dbmodel.Metadata, error)
+}
+
+type HistoryRepo interface {
+	Add(ctx context.Context, e historymodel.Entry) error
+	GetList(ctx context.Context, f historymodel.Filter) ([]*historymodel.Entry, error)
+	GetByID(ctx context.Context, id string) (*historymodel.Entry, error)
+}
+
+// cancelTimeout ограничивает отмену на стороне сервера: она выполняется на соединении из общего пула
+const cancelTimeout = 5 * time.Second
+
+type running struct {
+	querymodel.Running
+	db     *dbmodel.DB
+	cancel context.CancelFunc
+	pid    int64
 }
 
 type service struct {
-	dbService DBService
+	dbService   DBService
+	historyRepo HistoryRepo
+	cfg         config.History
+	console     config.Console
+
+	mu      sync.Mutex
+	running map[string]*running
 }
 
-func (s *service) Execute(ctx context.Context, info querymodel.Info, id string) (querymodel.QueryResult, error) {
-	zap.S().Info("попытка выполнить запрос")
+// Execute выполняет запрос под идентификатором qid, по которому его можно отменить;
+// пустой qid заменяется сгенерированным.
+func (s *service) Execute(ctx context.Context, info querymodel.Info, id, qid string) (querymodel.QueryResult, error) {
+	zap.S().Info("попытка выполнить запрос", zap.String("qid", qid))
 
 	db, err := s.dbService.GetByID(id)
 	if err != nil {
 		return querymodel.QueryResult{}, err
 	}
 
-	q := querymodel.New(info, db.B())
+	if err = s.resolve(ctx, id, &info); err != nil {
+		zap.S().Error(err, zap.String("qid", qid))
+		return querymodel.QueryResult{}, err
+	}
+
+	if len(qid) == 0 {
+		qid = uuid.NewString()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r := &running{
+		Running: querymodel.Running{ID: qid, DBID: id, Info: info, StartedAt: time.Now().UTC()},
+		db:      db,
+		cancel:  cancel,
+	}
+
+	//список выполняемых запросов скрывает значения так же, как история
+	if s.cfg.RedactArgs {
+		r.Info, r.Redacted = info.Redact(), true
+	}
+
+	if err = s.register(r); err != nil {
+		zap.S().Error(err, zap.String("qid", qid))
+		return querymodel.QueryResult{}, err
+	}
+	defer s.unregister(qid)
+
+	q := querymodel.New(info, db.B(), db.Dialect())
+
+	e := historymodel.Entry{
+		ID:        uuid.NewString(),
+		DBID:      id,
+		Info:      info,
+		CreatedAt: r.StartedAt,
+	}
+
+	var args []any
+	if e.Query, args, err = q.ToSql(); err == nil && !s.cfg.RedactArgs {
+		e.Args = args
+	}
+
+	//значения попадают и в аргументы, и в сам запрос Info
+	if s.cfg.RedactArgs {
+		e.Info, e.Redacted = info.Redact(), true
+	}
 
 	var result querymodel.QueryResult
-	if result, err = q.Execute(ctx, db); err != nil {
+	result, err = s.execute(ctx, r, q)
+	e.Duration = time.Since(e.CreatedAt)
+	e.RowCount = result.RowCount
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	s.record(context.WithoutCancel(ctx), e)
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			err = errors.New("запрос отменён")
+		}
 		err = fmt.Errorf("не удалось выполнить запрос: %s", err)
-		zap.S().Error(err)
+		zap.S().Error(err, zap.String("qid", qid))
+		return querymodel.QueryResult{}, err
+	}
+
+	zap.S().Info("запрос выполнен успешно", zap.String("qid", qid))
+	return result, nil
+}
+
+// resolve проверяет запрос по кешированным метаданным, не обращаясь к базе данных.
+func (s *service) resolve(ctx context.Context, id string, info *querymodel.Info) error {
+	m, err := s.dbService.Metadata(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = info.Resolve(m.TableList, m.FunctionList); err != nil {
+		return fmt.Errorf("%w: %s", querymodel.ErrInvalid, err)
+	}
+
+	return nil
+}
+
+// execute выполняет запрос на выделенном соединении, чтобы знать его серверный процесс.
+func (s *service) execute(ctx context.Context, r *running, q querymodel.Query) (querymodel.QueryResult, error) {
+	conn, pid, err := r.db.Conn(ctx)
+	if err != nil {
+		return querymodel.QueryResult{}, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	s.setPID(r, pid)
+	defer s.setPID(r, 0)
+
+	return q.Execute(ctx, conn)
+}
+
+// ExecuteRaw выполняет произвольный SQL в отдельной транзакции: только для чтения, если база данных
+// не разрешает запись, с ограничением времени выполнения и количества возвращаемых строк.
+func (s *service) ExecuteRaw(ctx context.Context, sql, id, qid string) (querymodel.QueryResult, error) {
+	zap.S().Info("попытка выполнить произвольный запрос", zap.String("qid", qid))
+
+	db, err := s.dbService.GetByID(id)
+	if err != nil {
+		return querymodel.QueryResult{}, err
+	}
+
+	var statement querymodel.Statement
+	if statement, err = querymodel.NewStatement(sql, db.GetInfo().Config.Driver); err != nil {
+		zap.S().Error(fmt.Errorf("запрос не прошёл проверку: %s", err), zap.String("qid", qid))
+		return querymodel.QueryResult{}, err
+	}
+
+	if len(qid) == 0 {
+		qid = uuid.NewString()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	//ограничение на стороне клиента для драйверов, где нельзя задать его на сервере
+	if s.console.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.console.Timeout)
+		defer cancel()
+	}
+
+	r := &running{
+		Running: querymodel.Running{ID: qid, DBID: id, Query: sql, StartedAt: time.Now().UTC()},
+		db:      db,
+		cancel:  cancel,
+	}
+
+	if s.cfg.RedactArgs {
+		r.Query, r.Redacted = statement.Redact(), true
+	}
+
+	if err = s.register(r); err != nil {
+		zap.S().Error(err, zap.String("qid", qid))
+		return querymodel.QueryResult{}, err
+	}
+	defer s.unregister(qid)
+
+	e := historymodel.Entry{
+		ID:        uuid.NewString(),
+		DBID:      id,
+		Query:     sql,
+		Raw:       true,
+		CreatedAt: r.StartedAt,
+	}
+
+	//значения в произвольном SQL записаны прямо в тексте запроса
+	if s.cfg.RedactArgs {
+		e.Query, e.Redacted = statement.Redact(), true
+	}
+
+	var result querymodel.QueryResult
+	result, err = s.executeRaw(ctx, r, statement)
+	e.Duration = time.Since(e.CreatedAt)
+	e.RowCount = result.RowCount
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	s.record(context.WithoutCancel(ctx), e)
+
+	if err != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			err = errors.New("превышено время выполнения запроса")
+		case errors.Is(ctx.Err(), context.Canceled):
+			err = errors.New("запрос отменён")
+		}
+		err = fmt.Errorf("не удалось выполнить запрос: %s", err)
+		zap.S().Error(err, zap.String("qid", qid))
+		return querymodel.QueryResult{}, err
+	}
+
+	zap.S().Info("произвольный запрос выполнен успешно", zap.String("qid", qid))
+	return result, nil
+}
+
+func (s *service) executeRaw(ctx context.Context, r *running, statement querymodel.Statement) (querymodel.QueryResult, error) {
+	conn, pid, err := r.db.Conn(ctx)
+	if err != nil {
+		return querymodel.QueryResult{}, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	defer func() {
+		if err := r.db.ResetSession(context.WithoutCancel(ctx), conn); err != nil {
+			zap.S().Errorf("не удалось сбросить состояние сеанса: %s", err)
+		}
+	}()
+
+	s.setPID(r, pid)
+	defer s.setPID(r, 0)
+
+	tx, err := r.db.BeginConsole(ctx, conn, s.console.Timeout)
+	if err != nil {
+		return querymodel.QueryResult{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var result querymodel.QueryResult
+	if result, err = statement.Execute(ctx, tx, r.db.Dialect(), s.console.RowLimit); err != nil {
+		return querymodel.QueryResult{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return querymodel.QueryResult{}, err
 	}
 
-	zap.S().Info("запрос выполнен успешно")
 	return result, nil
 }
 
-func New(dbService DBService) *service {
-	return &service{dbService: dbService}
+// setPID запоминает серверный процесс выделенного соединения; до возврата соединения в пул
+// он сбрасывается, чтобы отмена не попала в чужой запрос на том же процессе.
+func (s *service) setPID(r *running, pid int64) {
+	s.mu.Lock()
+	r.pid = pid
+	s.mu.Unlock()
+}
+
+func (s *service) register(r *running) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.running[r.ID]; ok {
+		return fmt.Errorf("запрос %s уже выполняется", r.ID)
+	}
+
+	s.running[r.ID] = r
+	return nil
+}
+
+func (s *service) unregister(qid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, qid)
+}
+
+func (s *service) RunningList() []querymodel.Running {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]querymodel.Running, 0, len(s.running))
+	for _, r := range s.running {
+		list = append(list, r.Running)
+	}
+	return list
+}
+
+func (s *service) Cancel(ctx context.Context, qid string) error {
+	zap.S().Info("попытка отменить запрос", zap.String("qid", qid))
+
+	s.mu.Lock()
+	r, ok := s.running[qid]
+	s.mu.Unlock()
+
+	if !ok {
+		err := errors.New("запрос не выполняется")
+		zap.S().Error(err, zap.String("qid", qid))
+		return err
+	}
+
+	//сначала отменяется контекст: если пул ограничен одним соединением, его занимает сам запрос
+	r.cancel()
+
+	s.mu.Lock()
+	pid := r.pid
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, cancelTimeout)
+	defer cancel()
+
+	if err := r.db.CancelBackend(ctx, pid); err != nil {
+		zap.S().Errorf("не удалось отменить запрос на стороне сервера: %s", err)
+	}
+
+	zap.S().Info("запрос успешно отменён", zap.String("qid", qid))
+	return nil
+}
+
+// record сохраняет запись в историю; ошибка сохранения не влияет на результат запроса.
+func (s *service) record(ctx context.Context, e historymodel.Entry) {
+	if err := s.historyRepo.Add(ctx, e); err != nil {
+		zap.S().Errorf("не удалось сохранить запрос в историю: %s", err)
+	}
+}
+
+func (s *service) History(ctx context.Context, f historymodel.Filter) ([]*historymodel.Entry, error) {
+	zap.S().Info("попытка получить историю запросов")
+
+	list, err := s.historyRepo.GetList(ctx, f)
+	if err != nil {
+		err = fmt.Errorf("не удалось получить историю запросов: %s", err)
+		zap.S().Error(err)
+		return nil, err
+	}
+
+	zap.S().Info("история запросов успешно получена")
+	return list, nil
+}
+
+func (s *service) Rerun(ctx context.Context, hid, qid string) (querymodel.QueryResult, error) {
+	zap.S().Info("попытка повторно выполнить запрос из истории", zap.String("hid", hid))
+
+	e, err := s.historyRepo.GetByID(ctx, hid)
+	if err != nil {
+		err = fmt.Errorf("не удалось получить запрос из истории: %s", err)
+		zap.S().Error(err, zap.String("hid", hid))
+		return querymodel.QueryResult{}, err
+	}
+
+	if e.Redacted {
+		zap.S().Error(historymodel.ErrRedacted, zap.String("hid", hid))
+		return querymodel.QueryResult{}, historymodel.ErrRedacted
+	}
+
+	if e.Raw {
+		return s.ExecuteRaw(ctx, e.Query, e.DBID, qid)
+	}
+
+	return s.Execute(ctx, e.Info, e.DBID, qid)
+}
+
+// Import разбирает SELECT и сопоставляет его с таблицами и функциями базы данных.
+// Ошибки разбора возвращаются как *querymodel.ParseError.
+func (s *service) Import(ctx context.Context, sql, id string) (querymodel.Info, error) {
+	zap.S().Info("попытка импортировать запрос", zap.String("id", id))
+
+	m, err := s.dbService.Metadata(ctx, id)
+	if err != nil {
+		return querymodel.Info{}, err
+	}
+
+	var info querymodel.Info
+	if info, err = querymodel.Parse(sql, m.TableList, m.FunctionList); err != nil {
+		zap.S().Error(fmt.Errorf("не удалось импортировать запрос: %s", err), zap.String("id", id))
+		return querymodel.Info{}, err
+	}
+
+	zap.S().Info("запрос успешно импортирован", zap.String("id", id))
+	return info, nil
+}
+
+func New(dbService DBService, historyRepo HistoryRepo, cfg config.History, console config.Console) *service {
+	return &service{
+		dbService:   dbService,
+		historyRepo: historyRepo,
+		cfg:         cfg,
+		console:     console,
+		running:     make(map[string]*running),
+	}
 }
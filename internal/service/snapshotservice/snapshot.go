@@ -0,0 +1,223 @@
+package snapshotservice
+
+import (
+	"context"
+	"datapoint/config"
+	"datapoint/internal/model/dbmodel"
+	"datapoint/internal/model/snapshotmodel"
+	"datapoint/pkg/database"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+type DBService interface {
+	GetList() []*dbmodel.DB
+	GetByID(id string) (*dbmodel.DB, error)
+	Refresh(ctx context.Context, id string) (*dbmodel.Metadata, error)
+}
+
+type SnapshotRepo interface {
+	Add(ctx context.Context, s *snapshotmodel.Snapshot) error
+	GetList(ctx context.Context, dbID string) ([]*snapshotmodel.Snapshot, error)
+	Last(ctx context.Context, dbID string) (*snapshotmodel.Snapshot, error)
+	GetByVersion(ctx context.Context, dbID string, version int) (*snapshotmodel.Snapshot, error)
+}
+
+// DBRepo проверяет, что база данных не удалена, в транзакции сохранения снимка.
+type DBRepo interface {
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+type service struct {
+	dbService DBService
+	r         SnapshotRepo
+	dbRepo    DBRepo
+	tx        database.TxManager
+	cfg       config.Snapshot
+	cancel    context.CancelFunc
+
+	mu sync.Mutex //снимки по расписанию и по запросу получают версии по порядку
+}
+
+// Take сохраняет снимок схемы базы данных. Если схема не изменилась с последнего снимка,
+// возвращается последний снимок и false. Схема читается вместе с кешем метаданных, чтобы
+// обнаруженное изменение сразу попало в ETag.
+func (s *service) Take(ctx context.Context, id string) (*snapshotmodel.Snapshot, bool, error) {
+	zap.S().Info("попытка сохранить снимок схемы базы данных", zap.String("id", id))
+
+	m, err := s.dbService.Refresh(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	tableList := m.TableList
+
+	var hash string
+	if hash, err = snapshotmodel.Hash(tableList); err != nil {
+		err = fmt.Errorf("не удалось вычислить отпечаток схемы: %s", err)
+		zap.S().Error(err, zap.String("id", id))
+		return nil, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		snapshot *snapshotmodel.Snapshot
+		created  bool
+	)
+
+	//база данных могла быть удалена, пока читалась схема; в транзакции снимок не переживёт её удаление
+	if err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		exists, err := s.dbRepo.Exists(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.New("базы данных не существует")
+		}
+
+		var last *snapshotmodel.Snapshot
+		if last, err = s.r.Last(ctx, id); err != nil {
+			return fmt.Errorf("не удалось получить последний снимок схемы: %s", err)
+		}
+
+		if last != nil && last.Hash == hash {
+			snapshot = last
+			return nil
+		}
+
+		snapshot = &snapshotmodel.Snapshot{
+			ID:        uuid.NewString(),
+			DBID:      id,
+			Version:   1,
+			Hash:      hash,
+			TableList: tableList,
+			CreatedAt: time.Now().UTC(),
+		}
+		if last != nil {
+			snapshot.Version = last.Version + 1
+		}
+
+		created = true
+		return s.r.Add(ctx, snapshot)
+	}); err != nil {
+		err = fmt.Errorf("не удалось сохранить снимок схемы: %s", err)
+		zap.S().Error(err, zap.String("id", id))
+		return nil, false, err
+	}
+
+	if !created {
+		zap.S().Info("схема базы данных не изменилась", zap.String("id", id), zap.Int("version", snapshot.Version))
+		return snapshot, false, nil
+	}
+
+	zap.S().Info("снимок схемы базы данных успешно сохранён", zap.String("id", id), zap.Int("version", snapshot.Version))
+	return snapshot, true, nil
+}
+
+func (s *service) GetList(ctx context.Context, id string) ([]*snapshotmodel.Snapshot, error) {
+	zap.S().Info("попытка получить снимки схемы базы данных", zap.String("id", id))
+
+	if _, err := s.dbService.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	list, err := s.r.GetList(ctx, id)
+	if err != nil {
+		err = fmt.Errorf("не удалось получить снимки схемы: %s", err)
+		zap.S().Error(err, zap.String("id", id))
+		return nil, err
+	}
+
+	zap.S().Info("снимки схемы базы данных успешно получены", zap.String("id", id))
+	return list, nil
+}
+
+func (s *service) GetByVersion(ctx context.Context, id string, version int) (*snapshotmodel.Snapshot, error) {
+	zap.S().Info("попытка получить снимок схемы базы данных", zap.String("id", id), zap.Int("version", version))
+
+	snapshot, err := s.r.GetByVersion(ctx, id, version)
+	if err != nil {
+		err = fmt.Errorf("не удалось получить снимок схемы версии %d: %w", version, err)
+		zap.S().Error(err, zap.String("id", id))
+		return nil, err
+	}
+
+	zap.S().Info("снимок схемы базы данных успешно получен", zap.String("id", id), zap.Int("version", version))
+	return snapshot, nil
+}
+
+// Diff сравнивает два снимка схемы базы данных по версиям.
+func (s *service) Diff(ctx context.Context, id string, from, to int) (snapshotmodel.Diff, error) {
+	fromSnapshot, err := s.GetByVersion(ctx, id, from)
+	if err != nil {
+		return snapshotmodel.Diff{}, err
+	}
+
+	var toSnapshot *snapshotmodel.Snapshot
+	if toSnapshot, err = s.GetByVersion(ctx, id, to); err != nil {
+		return snapshotmodel.Diff{}, err
+	}
+
+	return snapshotmodel.Compare(fromSnapshot, toSnapshot), nil
+}
+
+// schedule сохраняет снимки всех доступных баз данных с интервалом cfg.Interval.
+func (s *service) schedule(ctx context.Context) {
+	t := time.NewTicker(s.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.takeAll(ctx)
+		}
+	}
+}
+
+func (s *service) takeAll(ctx context.Context) {
+	for _, db := range s.dbService.GetList() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		//недоступную базу данных не имеет смысла ждать до таймаута
+		if db.Health().Status == dbmodel.Down {
+			continue
+		}
+
+		s.takeScheduled(ctx, db.ID)
+	}
+}
+
+func (s *service) takeScheduled(ctx context.Context, id string) {
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	_, _, _ = s.Take(ctx, id) //ошибки уже записаны в журнал
+}
+
+func (s *service) Close() {
+	s.cancel()
+}
+
+func New(dbService DBService, r SnapshotRepo, dbRepo DBRepo, tx database.TxManager, cfg config.Snapshot) *service {
+	s := &service{dbService: dbService, r: r, dbRepo: dbRepo, tx: tx, cfg: cfg}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	if cfg.Interval > 0 {
+		go s.schedule(ctx)
+	}
+
+	return s
+}
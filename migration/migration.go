@@ -2,6 +2,7 @@ package migration
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 )
 
@@ -9,12 +10,55 @@ type Executor interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// columns добавлены в таблицы после их создания. CREATE TABLE IF NOT EXISTS не меняет уже
+// существующую таблицу, поэтому каждая колонка добавляется отдельно, если её ещё нет.
+var columns = []column{
+	{"database", "function_list", "TEXT"},
+	{"database", "allow_write", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"database", "path", "TEXT"},
+	{"database", "read_only", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"database", "schema_list", "TEXT"},
+	{"database", "ssl_mode", "TEXT"},
+	{"database", "ssl_root_cert", "TEXT"},
+	{"database", "ssl_cert", "TEXT"},
+	{"database", "ssl_key", "TEXT"},
+	{"database", "ssh", "TEXT"},
+	{"database", "params", "TEXT"},
+	{"database", "conn_string", "TEXT"},
+	{"database", "pool", "TEXT"},
+	{"query_history", "redacted", "BOOLEAN NOT NULL DEFAULT FALSE"},
+}
+
 func FromFile(e Executor, name string) error {
 	data, err := os.ReadFile(name)
 	if err != nil {
 		return err
 	}
 
-	_, err = e.Exec(string(data))
-	return err
+	if _, err = e.Exec(string(data)); err != nil {
+		return err
+	}
+
+	return addColumns(e)
+}
+
+// addColumns проверяет колонку пустым запросом: так проверка одинакова для всех драйверов хранилища.
+func addColumns(e Executor) error {
+	for _, c := range columns {
+		if _, err := e.Exec(fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", c.name, c.table)); err == nil {
+			continue
+		}
+
+		if _, err := e.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)); err != nil {
+			return fmt.Errorf("не удалось добавить колонку %s.%s: %s", c.table, c.name, err)
+		}
+	}
+
+	return nil
 }
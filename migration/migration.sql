@@ -8,3 +8,27 @@ CREATE TABLE IF NOT EXISTS database (
     db_name TEXT NOT NULL,
     driver TEXT NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS query_history (
+    id UUID PRIMARY KEY,
+    database_id UUID NOT NULL,
+    query_type TEXT NOT NULL,
+    info TEXT NOT NULL,
+    query TEXT NOT NULL,
+    args TEXT,
+    duration BIGINT NOT NULL,
+    row_count BIGINT NOT NULL,
+    error TEXT,
+    caller TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS schema_snapshot (
+    id UUID PRIMARY KEY,
+    database_id UUID NOT NULL REFERENCES database (id),
+    version INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    table_list TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (database_id, version)
+);
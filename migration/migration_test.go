@@ -0,0 +1,41 @@
+package migration_test
+
+import (
+	"database/sql"
+	"datapoint/migration"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromFile(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "datapoint.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	//хранилище, созданное до появления дополнительных колонок
+	if _, err = db.Exec("CREATE TABLE database (id UUID PRIMARY KEY, name TEXT NOT NULL, host TEXT NOT NULL, " +
+		"port INTEGER NOT NULL, db_user TEXT NOT NULL, password TEXT, db_name TEXT NOT NULL, driver TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("INSERT INTO database VALUES ('1', 'old', 'localhost', 5432, 'postgres', NULL, 'postgres', 'postgres')"); err != nil {
+		t.Fatal(err)
+	}
+
+	//повторный запуск ничего не меняет
+	for i := 0; i < 2; i++ {
+		if err = migration.FromFile(db, "migration.sql"); err != nil {
+			t.Fatalf("запуск %d --> %s", i+1, err)
+		}
+	}
+
+	var functionList *string
+	if err = db.QueryRow("SELECT function_list FROM database WHERE id = '1'").Scan(&functionList); err != nil {
+		t.Fatalf("ожидались добавленные колонки: %s", err)
+	}
+	if functionList != nil {
+		t.Errorf("ожидалось значение по умолчанию, получено: %v", *functionList)
+	}
+}
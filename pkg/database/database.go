@@ -3,43 +3,71 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
-	_ "github.com/lib/pq"
+	"github.com/go-sql-driver/mysql"
 	_ "github.com/mattn/go-sqlite3"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	Postgres = "postgres"
+	Mysql    = "mysql"
 	Sqlite3  = "sqlite3"
 )
 
-var placeholders = map[string]sq.PlaceholderFormat{Postgres: sq.Dollar, Sqlite3: sq.Question}
+var placeholders = map[string]sq.PlaceholderFormat{Postgres: sq.Dollar, Mysql: sq.Question, Sqlite3: sq.Question}
 
 const txKey = "tx"
 
 type Database struct {
 	*sql.DB
-	B sq.StatementBuilderType
+	B        sq.StatementBuilderType
+	tunnel   *Tunnel
+	mysqlKey string //адрес подключения MySQL в mysqlTunnels
+	pool     PoolConfig
 }
 
-func New(driverName, dataSourceName string) (*Database, error) {
-	db := new(Database)
-	err := db.Open(driverName, dataSourceName)
+// New подключается к базе данных, через tunnel, если он не nil.
+func New(driverName, dataSourceName string, tunnel *Tunnel) (*Database, error) {
+	return NewContext(context.Background(), driverName, dataSourceName, tunnel, PoolConfig{})
+}
+
+// NewContext - New с ограничением времени подключения через ctx и настройками пула,
+// которые действуют уже для первого соединения.
+func NewContext(ctx context.Context, driverName, dataSourceName string, tunnel *Tunnel, pool PoolConfig) (*Database, error) {
+	db := &Database{pool: pool}
+	err := db.OpenContext(ctx, driverName, dataSourceName, tunnel)
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
-func (db *Database) Open(driverName, dataSourceName string) error {
-	d, err := sql.Open(driverName, dataSourceName)
-	if err != nil {
-		return err
+// Open заменяет подключение новым. Туннель переходит во владение подключения:
+// он закрывается вместе с подключением или сразу, если подключиться не удалось.
+func (db *Database) Open(driverName, dataSourceName string, tunnel *Tunnel) error {
+	return db.OpenContext(context.Background(), driverName, dataSourceName, tunnel)
+}
+
+func (db *Database) OpenContext(ctx context.Context, driverName, dataSourceName string, tunnel *Tunnel) error {
+	d, mysqlKey, err := open(driverName, dataSourceName, tunnel)
+	if err == nil {
+		applyPool(d, db.pool)
+		if err = d.PingContext(ctx); err != nil {
+			_ = d.Close()
+		}
 	}
 
-	if err = d.Ping(); err != nil {
-		_ = d.Close()
+	if err != nil {
+		unregisterMysqlTunnel(mysqlKey)
+		if tunnel != nil {
+			_ = tunnel.Close()
+		}
 		return err
 	}
 
@@ -47,7 +75,12 @@ func (db *Database) Open(driverName, dataSourceName string) error {
 		_ = db.DB.Close()
 	}
 
-	db.DB = d
+	if db.tunnel != nil {
+		_ = db.tunnel.Close()
+	}
+	unregisterMysqlTunnel(db.mysqlKey)
+
+	db.DB, db.tunnel, db.mysqlKey = d, tunnel, mysqlKey
 	db.B = sq.StatementBuilder.
 		RunWith(db).
 		PlaceholderFormat(placeholders[driverName])
@@ -55,8 +88,128 @@ func (db *Database) Open(driverName, dataSourceName string) error {
 	return nil
 }
 
+const defaultMaxIdle = 2 //как в database/sql
+
+// PoolConfig - настройки пула соединений; нулевое значение оставляет значение database/sql по умолчанию.
+type PoolConfig struct {
+	MaxOpen     int           //по умолчанию не ограничено
+	MaxIdle     int           //по умолчанию 2
+	MaxLifetime time.Duration //по умолчанию не ограничено
+	MaxIdleTime time.Duration //по умолчанию не ограничено
+}
+
+// SetPool применяет настройки пула к подключению; Open применяет их к новому подключению.
+func (db *Database) SetPool(cfg PoolConfig) {
+	db.pool = cfg
+	applyPool(db.DB, cfg)
+}
+
+func applyPool(d *sql.DB, cfg PoolConfig) {
+	//для database/sql ноль в MaxIdleConns означает «не хранить простаивающие соединения»
+	maxIdle := cfg.MaxIdle
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdle
+	}
+
+	d.SetMaxOpenConns(cfg.MaxOpen)
+	d.SetMaxIdleConns(maxIdle)
+	d.SetConnMaxLifetime(cfg.MaxLifetime)
+	d.SetConnMaxIdleTime(cfg.MaxIdleTime)
+}
+
 func (db *Database) Close() {
 	_ = db.DB.Close()
+	if db.tunnel != nil {
+		_ = db.tunnel.Close()
+	}
+	unregisterMysqlTunnel(db.mysqlKey)
+}
+
+// open подключается к базе данных; для MySQL через туннель также возвращает ключ в mysqlTunnels.
+func open(driverName, dataSourceName string, tunnel *Tunnel) (*sql.DB, string, error) {
+	//несколько серверов и target_session_attrs разбираются и без туннеля
+	if driverName == Postgres {
+		c, err := newPostgresConnector(dataSourceName, tunnel)
+		if err != nil {
+			return nil, "", err
+		}
+		return sql.OpenDB(c), "", nil
+	}
+
+	if tunnel == nil {
+		d, err := sql.Open(driverName, dataSourceName)
+		return d, "", err
+	}
+
+	switch driverName {
+	case Mysql:
+		cfg, err := mysql.ParseDSN(dataSourceName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		key := registerMysqlTunnel(tunnel, cfg.Addr)
+		cfg.Net, cfg.Addr = mysqlTunnelNet, key
+
+		c, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, key, err
+		}
+		return sql.OpenDB(c), key, nil
+	}
+
+	return nil, "", fmt.Errorf("драйвер %s не поддерживает SSH-туннель", driverName)
+}
+
+// Драйвер MySQL выбирает функцию подключения по имени сети из глобального реестра, из которого нельзя удалять.
+// Поэтому сеть регистрируется один раз, а туннель и настоящий адрес находятся по адресу из конфигурации драйвера.
+const mysqlTunnelNet = "ssh"
+
+type mysqlTunnel struct {
+	tunnel *Tunnel
+	addr   string
+}
+
+var (
+	mysqlDialOnce  sync.Once
+	mysqlTunnelsMu sync.RWMutex
+	mysqlTunnels   = make(map[string]mysqlTunnel)
+	mysqlTunnelSeq atomic.Uint64
+)
+
+func registerMysqlTunnel(tunnel *Tunnel, addr string) string {
+	mysqlDialOnce.Do(func() {
+		mysql.RegisterDialContext(mysqlTunnelNet, dialMysqlTunnel)
+	})
+
+	key := fmt.Sprintf("tunnel-%d", mysqlTunnelSeq.Add(1))
+
+	mysqlTunnelsMu.Lock()
+	mysqlTunnels[key] = mysqlTunnel{tunnel: tunnel, addr: addr}
+	mysqlTunnelsMu.Unlock()
+
+	return key
+}
+
+func unregisterMysqlTunnel(key string) {
+	if len(key) == 0 {
+		return
+	}
+
+	mysqlTunnelsMu.Lock()
+	delete(mysqlTunnels, key)
+	mysqlTunnelsMu.Unlock()
+}
+
+func dialMysqlTunnel(ctx context.Context, key string) (net.Conn, error) {
+	mysqlTunnelsMu.RLock()
+	t, ok := mysqlTunnels[key]
+	mysqlTunnelsMu.RUnlock()
+	if !ok {
+		return nil, errors.New("SSH-туннель закрыт")
+	}
+
+	return t.tunnel.DialContext(ctx, "tcp", t.addr)
 }
 
 type TxHandler func(context.Context) error
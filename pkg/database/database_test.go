@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetPool(t *testing.T) {
+	db, err := New(Sqlite3, "file::memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.SetPool(PoolConfig{MaxOpen: 3, MaxLifetime: time.Minute})
+	if given := db.Stats().MaxOpenConnections; given != 3 {
+		t.Errorf("ожидалось не больше 3 соединений, получено: %d", given)
+	}
+
+	//настройки пула переносятся на новое подключение
+	if err = db.Open(Sqlite3, "file::memory:", nil); err != nil {
+		t.Fatal(err)
+	}
+	if given := db.Stats().MaxOpenConnections; given != 3 {
+		t.Errorf("после переподключения ожидалось не больше 3 соединений, получено: %d", given)
+	}
+
+	db.SetPool(PoolConfig{})
+	if given := db.Stats().MaxOpenConnections; given != 0 {
+		t.Errorf("ожидалось неограниченное число соединений, получено: %d", given)
+	}
+}
+
+func TestNewContextPool(t *testing.T) {
+	db, err := NewContext(context.Background(), Sqlite3, "file::memory:", nil, PoolConfig{MaxOpen: 1, MaxIdle: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	//первое соединение открывается уже с настройками пула
+	if s := db.Stats(); s.MaxOpenConnections != 1 || s.OpenConnections != 1 || s.Idle != 1 {
+		t.Errorf("ожидалось одно простаивающее соединение из одного, получено: %+v", s)
+	}
+}
@@ -0,0 +1,361 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Значения target_session_attrs, как в libpq.
+const (
+	SessionAny           = "any"
+	SessionReadWrite     = "read-write"
+	SessionReadOnly      = "read-only"
+	SessionPrimary       = "primary"
+	SessionStandby       = "standby"
+	SessionPreferStandby = "prefer-standby"
+)
+
+var sessionAttrs = map[string]bool{
+	SessionAny: true, SessionReadWrite: true, SessionReadOnly: true,
+	SessionPrimary: true, SessionStandby: true, SessionPreferStandby: true,
+}
+
+// pgHost - строка подключения lib/pq к одному серверу из списка host.
+type pgHost struct {
+	host, port string
+	dsn        string
+}
+
+func (h pgHost) addr() string {
+	return h.host + ":" + h.port
+}
+
+// splitPostgresDSN разбирает строку подключения libpq, в том числе с несколькими серверами
+// (host=a,b port=5432,5433) и target_session_attrs, которые lib/pq не поддерживает.
+// Возвращает строку подключения для каждого сервера и target_session_attrs.
+func splitPostgresDSN(dsn string) ([]pgHost, string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return nil, "", err
+		}
+	}
+
+	options, err := parseOptions(dsn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	attrs := SessionAny
+	hosts, ports := []string{""}, []string{""}
+	rest := make([][2]string, 0, len(options))
+	for _, o := range options {
+		switch o[0] {
+		case "target_session_attrs":
+			attrs = o[1]
+		case "host":
+			hosts = strings.Split(o[1], ",")
+		case "port":
+			ports = strings.Split(o[1], ",")
+		default:
+			rest = append(rest, o)
+		}
+	}
+
+	if !sessionAttrs[attrs] {
+		return nil, "", fmt.Errorf("недопустимое значение target_session_attrs: %s", attrs)
+	}
+
+	if len(ports) != 1 && len(ports) != len(hosts) {
+		return nil, "", fmt.Errorf("указано серверов: %d, а портов: %d", len(hosts), len(ports))
+	}
+
+	list := make([]pgHost, 0, len(hosts))
+	for i, host := range hosts {
+		port := ports[0]
+		if len(ports) != 1 {
+			port = ports[i]
+		}
+
+		o := append([][2]string{{"host", host}, {"port", port}}, rest...)
+		list = append(list, pgHost{host: host, port: port, dsn: formatOptions(o)})
+	}
+
+	return list, attrs, nil
+}
+
+// PostgresAddrList возвращает адреса серверов из строки подключения libpq. Порт по умолчанию - 5432,
+// сервер - localhost; серверы, к которым подключаются через сокет, пропускаются.
+func PostgresAddrList(dsn string) ([]string, error) {
+	hosts, _, err := splitPostgresDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []string
+	for _, h := range hosts {
+		if strings.HasPrefix(h.host, "/") {
+			continue
+		}
+
+		host, port := h.host, h.port
+		if len(host) == 0 {
+			host = "localhost"
+		}
+		if len(port) == 0 {
+			port = "5432"
+		}
+		list = append(list, net.JoinHostPort(host, port))
+	}
+	return list, nil
+}
+
+// parseOptions разбирает строку подключения в формате ключ=значение с учётом кавычек и экранирования.
+func parseOptions(dsn string) ([][2]string, error) {
+	var (
+		r       = []rune(dsn)
+		options [][2]string
+	)
+
+	skipSpace := func(i int) int {
+		for i < len(r) && unicode.IsSpace(r[i]) {
+			i++
+		}
+		return i
+	}
+
+	for i := skipSpace(0); i < len(r); i = skipSpace(i) {
+		start := i
+		for i < len(r) && r[i] != '=' && !unicode.IsSpace(r[i]) {
+			i++
+		}
+		key := string(r[start:i])
+
+		if i = skipSpace(i); i >= len(r) || r[i] != '=' {
+			return nil, fmt.Errorf("после параметра %s ожидался знак =", key)
+		}
+		i = skipSpace(i + 1)
+
+		var (
+			value  []rune
+			quoted = i < len(r) && r[i] == '\''
+		)
+		if quoted {
+			i++
+		}
+
+		for ; i < len(r); i++ {
+			if quoted && r[i] == '\'' || !quoted && unicode.IsSpace(r[i]) {
+				break
+			}
+			if r[i] == '\\' && i+1 < len(r) {
+				i++
+			}
+			value = append(value, r[i])
+		}
+
+		if quoted {
+			if i >= len(r) {
+				return nil, fmt.Errorf("не закрыта кавычка в значении параметра %s", key)
+			}
+			i++
+		}
+
+		options = append(options, [2]string{key, string(value)})
+	}
+
+	return options, nil
+}
+
+func formatOptions(options [][2]string) string {
+	r := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+	list := make([]string, 0, len(options))
+	for _, o := range options {
+		if len(o[1]) == 0 && (o[0] == "host" || o[0] == "port") {
+			continue //значение по умолчанию lib/pq
+		}
+		list = append(list, o[0]+"='"+r.Replace(o[1])+"'")
+	}
+	return strings.Join(list, " ")
+}
+
+// newPostgresConnector подключается через lib/pq. Если серверов несколько или задан target_session_attrs,
+// серверы перебираются по порядку до первого подходящего, как в libpq.
+func newPostgresConnector(dsn string, tunnel *Tunnel) (driver.Connector, error) {
+	hosts, attrs, err := splitPostgresDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	var d pgDialer = netDialer{}
+	if tunnel != nil {
+		d = tunnel
+	}
+
+	c := &pgConnector{attrs: attrs}
+	for _, h := range hosts {
+		var hc *pq.Connector
+		if hc, err = pq.NewConnector(h.dsn); err != nil {
+			return nil, err
+		}
+		hc.Dialer(ctxDialer{d})
+		c.hosts = append(c.hosts, pgHostConnector{addr: h.addr(), c: hc})
+	}
+
+	return c, nil
+}
+
+type pgHostConnector struct {
+	addr string
+	c    *pq.Connector
+}
+
+type pgConnector struct {
+	hosts []pgHostConnector
+	attrs string
+}
+
+func (c *pgConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if len(c.hosts) == 1 && c.attrs == SessionAny {
+		return connectContext(ctx, c.hosts[0].c)
+	}
+
+	//prefer-standby: сначала ищется резервный сервер, затем подходит любой
+	passes := []string{c.attrs}
+	if c.attrs == SessionPreferStandby {
+		passes = []string{SessionStandby, SessionAny}
+	}
+
+	var errs []error
+	for _, attrs := range passes {
+		for _, h := range c.hosts {
+			conn, err := connectContext(ctx, h.c)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", h.addr, err))
+				continue
+			}
+
+			var ok bool
+			if ok, err = matchSession(ctx, conn, attrs); ok {
+				return conn, nil
+			}
+			_ = conn.Close()
+
+			if err == nil {
+				err = fmt.Errorf("сервер не подходит под target_session_attrs=%s", c.attrs)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", h.addr, err))
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (c *pgConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+type pgDialer interface {
+	pq.Dialer
+	pq.DialerContext
+}
+
+type netDialer struct{}
+
+func (netDialer) Dial(network, addr string) (net.Conn, error) {
+	return net.Dial(network, addr)
+}
+
+func (netDialer) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(network, addr, timeout)
+}
+
+func (netDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return (&net.Dialer{}).DialContext(ctx, network, addr)
+}
+
+// connectWatch связывает подключение с его контекстом: lib/pq ограничивает контекстом только
+// установку TCP-соединения, а рукопожатие с молчащим сервером ждёт бесконечно.
+type connectWatch struct {
+	ctx  context.Context
+	stop func() bool
+}
+
+type connectWatchKey struct{}
+
+// ctxDialer прерывает рукопожатие, когда отменяется контекст подключения.
+type ctxDialer struct {
+	pgDialer
+}
+
+func (d ctxDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.pgDialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	//ctx может быть уже сокращён connect_timeout, поэтому отслеживается контекст самого подключения
+	if w, ok := ctx.Value(connectWatchKey{}).(*connectWatch); ok {
+		w.stop = context.AfterFunc(w.ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	}
+	return conn, nil
+}
+
+// connectContext подключается к одному серверу с учётом ctx на всё время рукопожатия.
+func connectContext(ctx context.Context, c *pq.Connector) (driver.Conn, error) {
+	w := &connectWatch{ctx: ctx}
+	conn, err := c.Connect(context.WithValue(ctx, connectWatchKey{}, w))
+
+	//после отмены у соединения остаётся прошедший срок, и пользоваться им нельзя
+	if w.stop != nil && !w.stop() {
+		if err == nil {
+			_ = conn.Close()
+		}
+		return nil, ctx.Err()
+	}
+	return conn, err
+}
+
+// matchSession проверяет сервер теми же запросами, что и libpq.
+func matchSession(ctx context.Context, conn driver.Conn, attrs string) (bool, error) {
+	switch attrs {
+	case SessionReadWrite, SessionReadOnly:
+		v, err := queryValue(ctx, conn, "SHOW transaction_read_only")
+		return err == nil && (v == "on") == (attrs == SessionReadOnly), err
+	case SessionPrimary, SessionStandby:
+		v, err := queryValue(ctx, conn, "SELECT pg_is_in_recovery()")
+		return err == nil && (v == "true") == (attrs == SessionStandby), err
+	}
+	return true, nil
+}
+
+func queryValue(ctx context.Context, conn driver.Conn, query string) (string, error) {
+	q, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return "", errors.New("драйвер не поддерживает запросы без подготовки")
+	}
+
+	rows, err := q.QueryContext(ctx, query, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+
+	dest := make([]driver.Value, 1)
+	if err = rows.Next(dest); err != nil {
+		return "", err
+	}
+
+	if b, ok := dest[0].([]byte); ok {
+		return string(b), nil
+	}
+	return fmt.Sprint(dest[0]), nil
+}
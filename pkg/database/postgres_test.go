@@ -0,0 +1,82 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPostgresDSN(t *testing.T) {
+	tests := [...]struct {
+		dsn           string
+		expected      []pgHost
+		expectedAttrs string
+		expectedErr   bool
+	}{
+		{
+			dsn:           `host='localhost' port='5432' user='user' password='it\'s \\ p@ss' dbname='db'`,
+			expected:      []pgHost{{host: "localhost", port: "5432", dsn: `host='localhost' port='5432' user='user' password='it\'s \\ p@ss' dbname='db'`}},
+			expectedAttrs: SessionAny,
+		},
+		{
+			dsn: "host=db1,db2 port=5432 dbname = db target_session_attrs=read-write",
+			expected: []pgHost{
+				{host: "db1", port: "5432", dsn: `host='db1' port='5432' dbname='db'`},
+				{host: "db2", port: "5432", dsn: `host='db2' port='5432' dbname='db'`},
+			},
+			expectedAttrs: SessionReadWrite,
+		},
+		{
+			dsn: "host=db1,db2 port=5432,5433 target_session_attrs=prefer-standby",
+			expected: []pgHost{
+				{host: "db1", port: "5432", dsn: `host='db1' port='5432'`},
+				{host: "db2", port: "5433", dsn: `host='db2' port='5433'`},
+			},
+			expectedAttrs: SessionPreferStandby,
+		},
+		{
+			dsn:           "postgres://user@localhost:5433/db?target_session_attrs=primary",
+			expected:      []pgHost{{host: "localhost", port: "5433", dsn: `host='localhost' port='5433' dbname='db' user='user'`}},
+			expectedAttrs: SessionPrimary,
+		},
+		{dsn: "host=db1,db2,db3 port=5432,5433", expectedErr: true},
+		{dsn: "host=localhost target_session_attrs=master", expectedErr: true},
+		{dsn: "host='localhost", expectedErr: true},
+		{dsn: "host", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		hosts, attrs, err := splitPostgresDSN(test.dsn)
+		if (err != nil) != test.expectedErr {
+			t.Errorf("%s --> ожидалась ошибка: %t, получено: %v", test.dsn, test.expectedErr, err)
+			continue
+		}
+
+		if err == nil && (!reflect.DeepEqual(hosts, test.expected) || attrs != test.expectedAttrs) {
+			t.Errorf("%s --> ожидалось: %v, %s, получено: %v, %s", test.dsn, test.expected, test.expectedAttrs, hosts, attrs)
+		}
+	}
+}
+
+func TestPostgresAddrList(t *testing.T) {
+	tests := [...]struct {
+		dsn      string
+		expected []string
+	}{
+		{dsn: "host=db1,db2 port=5432,5433", expected: []string{"db1:5432", "db2:5433"}},
+		{dsn: "dbname=db", expected: []string{"localhost:5432"}},
+		{dsn: "host=/var/run/postgresql,db2", expected: []string{"db2:5432"}},
+		{dsn: "postgres://user@[::1]:6432/db", expected: []string{"[::1]:6432"}},
+	}
+
+	for _, test := range tests {
+		list, err := PostgresAddrList(test.dsn)
+		if err != nil {
+			t.Errorf("%s --> ошибка: %s", test.dsn, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(list, test.expected) {
+			t.Errorf("%s --> ожидалось: %v, получено: %v", test.dsn, test.expected, list)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type SSHConfig struct {
+	Host            string
+	Port            uint16
+	User            string
+	Password        string
+	PrivateKey      string //PEM
+	Passphrase      string //для зашифрованного PrivateKey
+	KnownHosts      string //строки в формате known_hosts
+	InsecureHostKey bool   //не проверять ключ сервера, если KnownHosts не указан
+}
+
+const sshTimeout = 10 * time.Second
+
+// Tunnel - SSH-соединение с сервером-посредником, через которое открываются соединения с базой данных.
+// SSH-соединение устанавливается заново, если оборвалось.
+type Tunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+	closed bool
+}
+
+func NewTunnel(cfg SSHConfig) (*Tunnel, error) {
+	var auth []ssh.AuthMethod
+
+	if len(cfg.PrivateKey) != 0 {
+		var (
+			signer ssh.Signer
+			err    error
+		)
+		if len(cfg.Passphrase) != 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(cfg.PrivateKey), []byte(cfg.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("некорректный закрытый ключ SSH: %s", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	if len(cfg.Password) != 0 {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+
+	if len(auth) == 0 {
+		return nil, errors.New("не указан ни закрытый ключ, ни пароль SSH")
+	}
+
+	hostKeyCallback, err := hostKeyCallback(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tunnel{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port))),
+		config: &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         sshTimeout,
+		},
+	}
+
+	//ошибки подключения и аутентификации видны сразу, а не при первом запросе
+	if _, err = t.connect(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func hostKeyCallback(cfg SSHConfig) (ssh.HostKeyCallback, error) {
+	if len(cfg.KnownHosts) == 0 {
+		if !cfg.InsecureHostKey {
+			return nil, errors.New("не указаны известные ключи сервера SSH")
+		}
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	//knownhosts читает только файлы и делает это сразу, поэтому временный файл удаляется после разбора
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.WriteString(cfg.KnownHosts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("некорректные известные ключи сервера SSH: %s", err)
+	}
+	return callback, nil
+}
+
+func (t *Tunnel) connect() (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, errors.New("SSH-туннель закрыт")
+	}
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	client, err := ssh.Dial("tcp", t.addr, t.config)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к серверу SSH: %s", err)
+	}
+
+	t.client = client
+	go func() {
+		_ = client.Wait()
+		t.mu.Lock()
+		if t.client == client {
+			t.client = nil
+		}
+		t.mu.Unlock()
+	}()
+
+	return client, nil
+}
+
+// DialContext открывает соединение с addr со стороны сервера SSH.
+func (t *Tunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect()
+	if err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, addr)
+}
+
+// Dial и DialTimeout реализуют pq.Dialer.
+func (t *Tunnel) Dial(network, addr string) (net.Conn, error) {
+	return t.DialContext(context.Background(), network, addr)
+}
+
+func (t *Tunnel) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return t.DialContext(ctx, network, addr)
+}
+
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	if t.client == nil {
+		return nil
+	}
+
+	err := t.client.Close()
+	t.client = nil
+	return err
+}
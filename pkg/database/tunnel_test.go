@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+)
+
+// TestTunnel поднимает локальный сервер SSH с перенаправлением портов (direct-tcpip)
+// и эхо-сервер, до которого открывается соединение через туннель.
+func TestTunnel(t *testing.T) {
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientPublic, clientKey, _ := ed25519.GenerateKey(rand.Reader)
+	clientSSHPublic, err := ssh.NewPublicKey(clientPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientSSHPublic.Marshal()) {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	echo := listen(t)
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _, _ = io.Copy(conn, conn); _ = conn.Close() }()
+		}
+	}()
+
+	server := listen(t)
+	go serveSSH(server, serverConfig)
+
+	host, port, _ := net.SplitHostPort(server.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	cfg := SSHConfig{
+		Host:       host,
+		Port:       uint16(p),
+		User:       "datapoint",
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		KnownHosts: knownhosts.Line([]string{server.Addr().String()}, hostSigner.PublicKey()) + "\n",
+	}
+
+	tunnel, err := NewTunnel(cfg)
+	if err != nil {
+		t.Fatalf("не удалось открыть туннель: %s", err)
+	}
+	defer func() { _ = tunnel.Close() }()
+
+	conn, err := tunnel.Dial("tcp", echo.Addr().String())
+	if err != nil {
+		t.Fatalf("не удалось подключиться через туннель: %s", err)
+	}
+
+	if _, err = conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4)
+	if _, err = io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("ожидался ответ ping, получено: %q, ошибка: %v", buf, err)
+	}
+	_ = conn.Close()
+
+	//MySQL подключается через одну зарегистрированную сеть, туннель находится по ключу
+	key := registerMysqlTunnel(tunnel, echo.Addr().String())
+	if conn, err = dialMysqlTunnel(context.Background(), key); err != nil {
+		t.Errorf("не удалось подключиться через туннель MySQL: %s", err)
+	} else {
+		_ = conn.Close()
+	}
+	unregisterMysqlTunnel(key)
+	if _, err = dialMysqlTunnel(context.Background(), key); err == nil {
+		t.Errorf("ожидалась ошибка подключения после удаления туннеля MySQL")
+	}
+
+	//эхо-сервер не отвечает по протоколу MySQL, поэтому подключение не удаётся и ключ удаляется
+	if _, err = New(Mysql, "user:password@tcp("+echo.Addr().String()+")/db?timeout=1s&readTimeout=1s", tunnel); err == nil {
+		t.Errorf("ожидалась ошибка подключения к эхо-серверу как к MySQL")
+	}
+	if len(mysqlTunnels) != 0 {
+		t.Errorf("ожидалось, что туннели MySQL будут удалены, осталось: %d", len(mysqlTunnels))
+	}
+
+	if tunnel, err = NewTunnel(cfg); err != nil {
+		t.Fatalf("не удалось открыть туннель: %s", err)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherKey)
+	cfg.KnownHosts = knownhosts.Line([]string{server.Addr().String()}, otherSigner.PublicKey()) + "\n"
+	if _, err = NewTunnel(cfg); err == nil {
+		t.Errorf("ожидалась ошибка для неизвестного ключа сервера")
+	}
+
+	cfg.KnownHosts = ""
+	if _, err = NewTunnel(cfg); err == nil {
+		t.Errorf("ожидалась ошибка без известных ключей сервера")
+	}
+
+	if err = tunnel.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tunnel.Dial("tcp", echo.Addr().String()); err == nil {
+		t.Errorf("ожидалась ошибка подключения через закрытый туннель")
+	}
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func serveSSH(l net.Listener, config *ssh.ServerConfig) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			_, channels, requests, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(requests)
+
+			for ch := range channels {
+				if ch.ChannelType() != "direct-tcpip" {
+					_ = ch.Reject(ssh.UnknownChannelType, "")
+					continue
+				}
+
+				var target struct {
+					Host       string
+					Port       uint32
+					OriginHost string
+					OriginPort uint32
+				}
+				if err = ssh.Unmarshal(ch.ExtraData(), &target); err != nil {
+					_ = ch.Reject(ssh.ConnectionFailed, err.Error())
+					continue
+				}
+
+				remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+				if err != nil {
+					_ = ch.Reject(ssh.ConnectionFailed, err.Error())
+					continue
+				}
+
+				channel, reqs, err := ch.Accept()
+				if err != nil {
+					_ = remote.Close()
+					continue
+				}
+				go ssh.DiscardRequests(reqs)
+
+				go func() { _, _ = io.Copy(channel, remote); _ = channel.CloseWrite() }()
+				go func() { _, _ = io.Copy(remote, channel); _ = remote.Close() }()
+			}
+		}()
+	}
+}